@@ -0,0 +1,165 @@
+/*
+Copyright © 2026 ARCoder181105 <EMAIL ADDRESS>
+*/
+
+// Package cmd implements the CLI commands.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ARCoder181105/netdiag/pkg/analyze"
+	"github.com/ARCoder181105/netdiag/pkg/logger"
+	"github.com/ARCoder181105/netdiag/pkg/output"
+	"github.com/ARCoder181105/netdiag/pkg/store"
+)
+
+var (
+	analyzeTarget string
+	analyzeType   string
+	analyzeWindow time.Duration
+	analyzeWorst  int
+	analyzeDetect bool
+	analyzeZ      float64
+)
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Report on stored probe history",
+	Long: `Build per-target health reports from results saved with --save or
+'netdiag monitor --save': probe count, uptime, latency percentiles and
+failures by severity over a time window.
+
+Examples:
+  netdiag analyze
+  netdiag analyze --target 10.0.0.1 --window 24h
+  netdiag analyze --worst 5 --window 168h
+  netdiag analyze --type http --json
+  netdiag analyze --target 10.0.0.1 --detect --anomaly-z 4`,
+	Args: cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		st, err := openStore()
+		if err != nil {
+			logger.Log.Error("analyze failed", "error", err)
+			output.PrintError(fmt.Sprintf("Failed to open history database: %v", err))
+			return
+		}
+		defer func() { _ = st.Close() }()
+
+		results, err := st.Query(context.Background(), store.Query{
+			Target:    analyzeTarget,
+			ProbeType: analyzeType,
+			Since:     time.Now().Add(-analyzeWindow),
+		})
+		if err != nil {
+			logger.Log.Error("analyze failed", "error", err)
+			output.PrintError(err.Error())
+			return
+		}
+
+		// Replay history through a fresh detector, so anomalies are counted
+		// even for results that were saved without 'monitor --anomaly'.
+		if analyzeDetect {
+			detector := &analyze.Detector{ZThreshold: analyzeZ}
+			for i := range results {
+				results[i].Anomaly = nil
+				detector.Observe(&results[i])
+			}
+		}
+
+		reports := analyze.BuildReports(results)
+		if analyzeWorst > 0 {
+			reports = analyze.Worst(reports, analyzeWorst)
+		}
+
+		logger.Log.Info("analyze completed",
+			"target", analyzeTarget,
+			"window", analyzeWindow.String(),
+			"results", len(results),
+			"reports", len(reports),
+		)
+
+		if jsonOutput {
+			output.PrintJSON(reports)
+			return
+		}
+
+		if len(reports) == 0 {
+			output.PrintWarning(fmt.Sprintf("No stored results in the last %s.", formatWindow(analyzeWindow)))
+			return
+		}
+
+		output.PrintInfo(fmt.Sprintf("Network Health Report — Last %s", formatWindow(analyzeWindow)))
+
+		headers := []string{
+			"Target", "Type", "Probes", "Uptime",
+			"Avg", "P50", "P95", "P99", "Warnings", "Errors", "Anomalies",
+		}
+		var rows [][]string
+
+		for _, r := range reports {
+			rows = append(rows, []string{
+				r.Target,
+				r.ProbeType,
+				fmt.Sprintf("%d", r.Probes),
+				fmt.Sprintf("%.2f%%", r.UptimePct),
+				formatLatency(r.AvgLatency),
+				formatLatency(r.P50Latency),
+				formatLatency(r.P95Latency),
+				formatLatency(r.P99Latency),
+				fmt.Sprintf("%d", r.Failures["Warning"]),
+				fmt.Sprintf("%d", r.Failures["Error"]+r.Failures["Unknown"]),
+				fmt.Sprintf("%d", r.Anomalies),
+			})
+		}
+
+		fmt.Println()
+		output.PrintTable(headers, rows)
+		fmt.Println()
+
+		for _, r := range reports {
+			if r.UptimePct < 99 {
+				output.PrintWarning(fmt.Sprintf(
+					"⚠ %s (%s) has elevated failure rate (%.1f%%). Investigate.",
+					r.Target, r.ProbeType, 100-r.UptimePct,
+				))
+			}
+		}
+	},
+}
+
+// formatLatency renders a latency for tables, using "-" when there is no data.
+func formatLatency(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return d.Round(10 * time.Microsecond).String()
+}
+
+// formatWindow renders whole-hour windows as "24h" rather than "24h0m0s",
+// dropping only the zero components Duration.String pads with.
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+func init() {
+	rootCmd.AddCommand(analyzeCmd)
+	analyzeCmd.Flags().StringVar(&analyzeTarget, "target", "", "Only report on this target")
+	analyzeCmd.Flags().StringVar(&analyzeType, "type", "", "Only report on this probe type (ping, http, dns, ...)")
+	analyzeCmd.Flags().DurationVarP(&analyzeWindow, "window", "w", 24*time.Hour, "How far back to look (e.g., 1h, 24h, 168h)")
+	analyzeCmd.Flags().IntVar(&analyzeWorst, "worst", 0, "Only show the N worst targets")
+	analyzeCmd.Flags().BoolVar(&analyzeDetect, "detect", false, "Re-run anomaly detection over the stored history")
+	analyzeCmd.Flags().Float64Var(&analyzeZ, "anomaly-z", analyze.DefaultZThreshold, "Z-score above which latency is flagged (with --detect)")
+}
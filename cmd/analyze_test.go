@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatWindow(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{10 * time.Second, "10s"},
+		{90 * time.Second, "1m30s"},
+		{70 * time.Second, "1m10s"},
+		{2 * time.Minute, "2m"},
+		{24 * time.Hour, "24h"},
+		{90 * time.Minute, "1h30m"},
+	}
+	for _, tt := range tests {
+		if got := formatWindow(tt.d); got != tt.want {
+			t.Errorf("formatWindow(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
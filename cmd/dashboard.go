@@ -0,0 +1,98 @@
+/*
+Copyright © 2026 ARCoder181105 <EMAIL ADDRESS>
+*/
+
+// Package cmd implements the CLI commands.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+
+	"github.com/ARCoder181105/netdiag/pkg/config"
+	"github.com/ARCoder181105/netdiag/pkg/logger"
+	"github.com/ARCoder181105/netdiag/pkg/monitor"
+	"github.com/ARCoder181105/netdiag/pkg/output"
+	"github.com/ARCoder181105/netdiag/pkg/tui"
+)
+
+var (
+	dashboardTargets      []string
+	dashboardType         string
+	dashboardInterval     time.Duration
+	dashboardProbeTimeout time.Duration
+)
+
+var dashboardCmd = &cobra.Command{
+	Use:   "dashboard [target...]",
+	Short: "Full-screen live dashboard of target health",
+	Long: `Open a full-screen dashboard that probes every target on an interval and
+shows a status table with latency sparklines, a detail pane for the selected
+host and a log of state changes.
+
+Targets use the same [type:]target syntax as 'netdiag monitor'.
+
+Keys:
+  ↑/↓ or k/j   select host
+  p or space   pause / resume probing
+  r            re-probe the selected host now
+  R            re-probe every host now
+  q            quit
+
+Examples:
+  netdiag dashboard google.com 1.1.1.1 https://github.com
+  netdiag dashboard --target 10.0.0.1 --target dns:example.com --interval 2s`,
+	Run: func(_ *cobra.Command, args []string) {
+		if dashboardInterval <= 0 {
+			output.PrintError("--interval must be a positive duration.")
+			return
+		}
+
+		specs := append(args, dashboardTargets...)
+		if len(specs) == 0 {
+			specs = config.AppConfig.Monitor.Targets
+		}
+		if len(specs) == 0 {
+			output.PrintError("No targets given. Pass hosts as arguments, --target, or set monitor.targets in the config file.")
+			return
+		}
+
+		var targets []monitor.Target
+		for _, s := range specs {
+			t, err := newMonitorTarget(s, dashboardType, dashboardProbeTimeout)
+			if err != nil {
+				output.PrintError(err.Error())
+				return
+			}
+			targets = append(targets, t)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		model := tui.New(ctx, targets, dashboardInterval)
+		program := tea.NewProgram(model, tea.WithAltScreen(), tea.WithContext(ctx))
+
+		logger.Log.Info("dashboard started", "targets", len(targets), "interval", dashboardInterval.String())
+
+		if _, err := program.Run(); err != nil && ctx.Err() == nil {
+			logger.Log.Error("dashboard failed", "error", err)
+			output.PrintError(fmt.Sprintf("Dashboard failed: %v", err))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dashboardCmd)
+	dashboardCmd.Flags().StringArrayVar(&dashboardTargets, "target", nil, "Target to display (repeatable)")
+	dashboardCmd.Flags().StringVar(&dashboardType, "type", "ping", "Default probe type (ping, http, dns, scan, trace)")
+	dashboardCmd.Flags().DurationVarP(&dashboardInterval, "interval", "i", 5*time.Second, "Time between probe rounds")
+	dashboardCmd.Flags().DurationVar(&dashboardProbeTimeout, "probe-timeout", 5*time.Second, "Timeout for each probe")
+}
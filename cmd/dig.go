@@ -23,12 +23,13 @@ var digTimeout int
 
 var digCmd = &cobra.Command{
 	Use:   "dig <domain> [type]",
-	Short: "Perform a DNS lookup (A, MX, TXT, NS, CNAME)",
+	Short: "Perform a DNS lookup (A, AAAA, MX, TXT, NS, CNAME)",
 	Long: `Perform a DNS lookup to find records for a domain.
 If no type is specified, it defaults to 'A'.
 
 Supported Record Types:
   A      : IPv4 Address
+  AAAA   : IPv6 Address
   MX     : Mail Exchange
   TXT    : Text Records
   NS     : Name Servers
@@ -37,7 +38,9 @@ Supported Record Types:
 Examples:
   netdiag dig google.com
   netdiag dig github.com MX
-  netdiag dig google.com TXT`,
+  netdiag dig google.com TXT
+  netdiag dig google.com AAAA
+  netdiag dig -6 example.com --server 2001:4860:4860::8888`,
 	Args: cobra.RangeArgs(1, 2),
 	Run: func(_ *cobra.Command, args []string) {
 
@@ -51,6 +54,7 @@ Examples:
 			Server:     digServer,
 			RecordType: recordType,
 			Timeout:    time.Duration(digTimeout) * time.Second,
+			Family:     addressFamily(),
 		}
 
 		result, err := prober.Probe(context.Background())
@@ -82,6 +86,8 @@ Examples:
 		}
 		// ─────────────────────────────────────────────────────────────────────
 
+		saveResults(result)
+
 		if jsonOutput {
 			output.PrintJSON(result)
 			return
@@ -22,6 +22,7 @@ var discoverCmd = &cobra.Command{
 
 		prober := &probe.DiscoverProber{
 			Timeout: time.Duration(discoverTimeout) * time.Millisecond,
+			Family:  addressFamily(),
 		}
 
 		result, err := prober.Probe(context.Background())
@@ -41,6 +42,7 @@ var discoverCmd = &cobra.Command{
 			logger.Log.Info("network discovery completed",
 				"prefix", result.DiscoverData.Prefix,
 				"devices_found", len(result.DiscoverData.Devices),
+				"mode", result.DiscoverData.Mode,
 				"latency_ms", result.Latency.Milliseconds(),
 			)
 		} else {
@@ -50,6 +52,8 @@ var discoverCmd = &cobra.Command{
 		}
 		// ─────────────────────────────────────────────────────────────────────
 
+		saveResults(result)
+
 		if jsonOutput {
 			output.PrintJSON(result)
 			return
@@ -68,15 +72,21 @@ var discoverCmd = &cobra.Command{
 		var rows [][]string
 
 		for _, dev := range data.Devices {
+			// Neighbors listed from the kernel's table were not timed.
+			latency := "-"
+			if dev.Latency > 0 {
+				latency = dev.Latency.String()
+			}
 			rows = append(rows, []string{
 				dev.IP,
 				dev.HostName,
-				dev.Latency.String(),
+				latency,
 			})
 		}
 
 		fmt.Println()
 		output.PrintTable(headers, rows)
+		warnPingMode(data.Mode)
 
 		switch result.Severity {
 		case probe.SeverityOK:
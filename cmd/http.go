@@ -48,6 +48,7 @@ Examples:
 			Method:        method,
 			Timeout:       time.Duration(timeOut) * time.Second,
 			SkipTLSVerify: skipTLS,
+			Family:        addressFamily(),
 		}
 
 		result, err := prober.Probe(context.Background())
@@ -80,6 +81,8 @@ Examples:
 		}
 		// ─────────────────────────────────────────────────────────────────────
 
+		saveResults(result)
+
 		if jsonOutput {
 			output.PrintJSON(result)
 			return
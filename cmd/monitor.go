@@ -0,0 +1,406 @@
+/*
+Copyright © 2026 ARCoder181105 <EMAIL ADDRESS>
+*/
+
+// Package cmd implements the CLI commands.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ARCoder181105/netdiag/pkg/alert"
+	"github.com/ARCoder181105/netdiag/pkg/analyze"
+	"github.com/ARCoder181105/netdiag/pkg/config"
+	"github.com/ARCoder181105/netdiag/pkg/logger"
+	"github.com/ARCoder181105/netdiag/pkg/metrics"
+	"github.com/ARCoder181105/netdiag/pkg/monitor"
+	"github.com/ARCoder181105/netdiag/pkg/output"
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+	"github.com/ARCoder181105/netdiag/pkg/store"
+)
+
+var (
+	monitorTargets      []string
+	monitorType         string
+	monitorInterval     time.Duration
+	monitorJitter       float64
+	monitorProbeTimeout time.Duration
+	monitorJSONLPath    string
+	monitorMetrics      bool
+	monitorMetricsAddr  string
+	monitorAnomaly      bool
+	monitorAnomalyZ     float64
+	monitorAnomalyHour  bool
+	monitorWebhooks     []string
+	monitorSlackWebhook string
+	monitorAlertExec    string
+	monitorCooldown     time.Duration
+	monitorAlertAfter   int
+	monitorResolveAfter int
+)
+
+var monitorCmd = &cobra.Command{
+	Use:   "monitor [target...]",
+	Short: "Continuously probe targets on an interval",
+	Long: `Run probes against one or more targets on a fixed interval until
+interrupted with Ctrl+C (SIGINT) or SIGTERM.
+
+A target may carry a probe type prefix and a per-target interval:
+
+  [type:]target[@interval]
+
+Supported types are ping, http, dns, scan and trace. URLs starting with
+http:// or https:// default to the http probe; everything else uses --type.
+If no targets are given, monitor.targets from ~/.netdiag.yaml is used, and
+--interval falls back to monitor.interval.
+
+Examples:
+  netdiag monitor google.com 1.1.1.1
+  netdiag monitor --target google.com --target https://github.com@1m
+  netdiag monitor dns:example.com@30s --interval 10s --jsonl results.jsonl
+  netdiag monitor google.com --metrics --metrics-addr :9100
+  netdiag monitor google.com --save
+  netdiag monitor 10.0.0.1 --anomaly --anomaly-z 4 --save
+  netdiag monitor 10.0.0.1 --slack-webhook https://hooks.slack.com/services/... --alert-after 3
+  netdiag monitor 10.0.0.1 --alert-exec ./page-oncall.sh`,
+	Run: func(cmd *cobra.Command, args []string) {
+		specs := append(args, monitorTargets...)
+		if len(specs) == 0 {
+			specs = config.AppConfig.Monitor.Targets
+		}
+		if len(specs) == 0 {
+			output.PrintError("No targets given. Pass hosts as arguments, --target, or set monitor.targets in the config file.")
+			return
+		}
+
+		interval := monitorInterval
+		if interval == 0 {
+			d, err := time.ParseDuration(config.AppConfig.Monitor.Interval)
+			if err != nil {
+				output.PrintError(fmt.Sprintf("Invalid monitor.interval in config: %v", err))
+				return
+			}
+			interval = d
+		}
+
+		var targets []monitor.Target
+		for _, s := range specs {
+			t, err := newMonitorTarget(s, monitorType, monitorProbeTimeout)
+			if err != nil {
+				output.PrintError(err.Error())
+				return
+			}
+			targets = append(targets, t)
+		}
+
+		sinks := []monitor.Sink{
+			&monitor.ConsoleSink{W: os.Stdout, JSON: jsonOutput},
+		}
+
+		if monitorJSONLPath != "" {
+			jsonl, err := monitor.NewJSONLSink(monitorJSONLPath)
+			if err != nil {
+				output.PrintError(fmt.Sprintf("Failed to open JSONL file: %v", err))
+				return
+			}
+			defer func() { _ = jsonl.Close() }()
+			sinks = append(sinks, jsonl)
+		}
+
+		var detector *analyze.Detector
+		if monitorAnomaly {
+			detector = &analyze.Detector{
+				ZThreshold: monitorAnomalyZ,
+				HourOfDay:  monitorAnomalyHour,
+			}
+		}
+
+		if saveOutput {
+			st, err := openStore()
+			if err != nil {
+				output.PrintError(fmt.Sprintf("Failed to open history database: %v", err))
+				return
+			}
+			defer func() { _ = st.Close() }()
+
+			if days := config.AppConfig.Database.RetentionDays; days > 0 {
+				deleted, err := st.Compact(context.Background(), time.Duration(days)*24*time.Hour)
+				if err != nil {
+					logger.Log.Warn("history compaction failed", "error", err)
+				} else if deleted > 0 {
+					logger.Log.Info("history compacted", "deleted", deleted, "retention_days", days)
+				}
+			}
+
+			// Warm the anomaly baselines up from recent history so detection
+			// works from the first tick instead of after MinSamples probes.
+			if detector != nil {
+				for _, t := range targets {
+					history, err := st.Query(context.Background(), store.Query{
+						Target:    t.Name,
+						ProbeType: t.Prober.Type(),
+						Since:     time.Now().Add(-7 * 24 * time.Hour),
+					})
+					if err != nil {
+						logger.Log.Warn("failed to load anomaly baseline", "target", t.Name, "error", err)
+						continue
+					}
+					detector.Seed(history)
+				}
+			}
+
+			sinks = append(sinks, monitor.SinkFunc(st.SaveResult))
+		}
+
+		alerts, err := newAlertManager(cmd)
+		if err != nil {
+			output.PrintError(err.Error())
+			return
+		}
+		if alerts != nil {
+			// Let alerts raised just before shutdown go out.
+			defer alerts.Close()
+			sinks = append(sinks, alerts)
+		}
+
+		mon := &monitor.Monitor{
+			Targets:  targets,
+			Sinks:    sinks,
+			Interval: interval,
+			Jitter:   monitorJitter,
+			Logger:   logger.Log,
+		}
+		if detector != nil {
+			mon.Processors = append(mon.Processors, detector)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Flags win over the config file only when set explicitly.
+		metricsEnabled := config.AppConfig.Metrics.Enabled
+		if cmd.Flags().Changed("metrics") {
+			metricsEnabled = monitorMetrics
+		}
+		metricsAddr := config.AppConfig.Metrics.Addr
+		if cmd.Flags().Changed("metrics-addr") || metricsAddr == "" {
+			metricsAddr = monitorMetricsAddr
+		}
+
+		if metricsEnabled {
+			reg := metrics.New()
+			mon.Sinks = append(mon.Sinks, reg)
+
+			go func() {
+				if err := metrics.ListenAndServe(ctx, metricsAddr, reg); err != nil {
+					logger.Log.Error("metrics server failed", "addr", metricsAddr, "error", err)
+					output.PrintError(fmt.Sprintf("Metrics server failed: %v", err))
+				}
+			}()
+			logger.Log.Info("metrics server listening", "addr", metricsAddr)
+		}
+
+		logger.Log.Info("monitor started",
+			"targets", len(targets),
+			"interval", interval.String(),
+		)
+
+		if !jsonOutput {
+			output.PrintInfo(fmt.Sprintf(
+				"Monitoring %d target(s) every %s. Press Ctrl+C to stop.",
+				len(targets), interval,
+			))
+		}
+
+		if err := mon.Run(ctx); err != nil {
+			logger.Log.Error("monitor failed", "error", err)
+			output.PrintError(err.Error())
+		}
+	},
+}
+
+// newAlertManager builds the alert manager from the config file and flags,
+// with flags taking precedence. It returns nil when no alerter is configured.
+func newAlertManager(cmd *cobra.Command) (*alert.Manager, error) {
+	cfg := config.AppConfig.Alert
+
+	var alerters []alert.Alerter
+	for _, url := range append(cfg.Webhooks, monitorWebhooks...) {
+		alerters = append(alerters, &alert.WebhookAlerter{URL: url})
+	}
+
+	slackURL := cfg.SlackWebhook
+	if monitorSlackWebhook != "" {
+		slackURL = monitorSlackWebhook
+	}
+	if slackURL != "" {
+		alerters = append(alerters, &alert.SlackAlerter{WebhookURL: slackURL})
+	}
+
+	execLine := cfg.Exec
+	if monitorAlertExec != "" {
+		execLine = monitorAlertExec
+	}
+	if fields := strings.Fields(execLine); len(fields) > 0 {
+		alerters = append(alerters, &alert.ExecAlerter{Command: fields[0], Args: fields[1:]})
+	}
+
+	if len(alerters) == 0 {
+		return nil, nil
+	}
+
+	cooldown := monitorCooldown
+	if !cmd.Flags().Changed("alert-cooldown") && cfg.Cooldown != "" {
+		d, err := time.ParseDuration(cfg.Cooldown)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alert.cooldown in config: %w", err)
+		}
+		cooldown = d
+	}
+
+	failAfter := monitorAlertAfter
+	if !cmd.Flags().Changed("alert-after") && cfg.FailThreshold > 0 {
+		failAfter = cfg.FailThreshold
+	}
+	resolveAfter := monitorResolveAfter
+	if !cmd.Flags().Changed("resolve-after") && cfg.ResolveThreshold > 0 {
+		resolveAfter = cfg.ResolveThreshold
+	}
+
+	return &alert.Manager{
+		Alerters:         alerters,
+		FailThreshold:    failAfter,
+		ResolveThreshold: resolveAfter,
+		Cooldown:         cooldown,
+		Logger:           logger.Log,
+	}, nil
+}
+
+// monitorProbeTypes lists the probe types that can be scheduled from the CLI.
+var monitorProbeTypes = map[string]bool{
+	"ping":  true,
+	"http":  true,
+	"dns":   true,
+	"scan":  true,
+	"trace": true,
+}
+
+// newMonitorTarget parses a "[type:]target[@interval]" spec into a
+// monitor.Target backed by a prober with sensible defaults.
+func newMonitorTarget(spec, defaultType string, timeout time.Duration) (monitor.Target, error) {
+	var t monitor.Target
+	raw := spec
+
+	if i := strings.LastIndex(spec, "@"); i > 0 {
+		if d, err := time.ParseDuration(spec[i+1:]); err == nil {
+			t.Interval = d
+			spec = spec[:i]
+		}
+	}
+
+	probeType := ""
+	if i := strings.Index(spec, ":"); i > 0 && monitorProbeTypes[spec[:i]] &&
+		!strings.HasPrefix(spec[i+1:], "//") {
+		probeType = spec[:i]
+		spec = spec[i+1:]
+	}
+	if probeType == "" {
+		if strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") {
+			probeType = "http"
+		} else {
+			probeType = defaultType
+		}
+	}
+
+	if spec == "" {
+		return t, fmt.Errorf("invalid target %q", raw)
+	}
+
+	prober, err := newProber(probeType, spec, timeout)
+	if err != nil {
+		return t, fmt.Errorf("invalid target %q: %w", raw, err)
+	}
+
+	t.Name = spec
+	t.Prober = prober
+	return t, nil
+}
+
+// newProber builds a prober of the given type with monitor-friendly defaults.
+func newProber(probeType, target string, timeout time.Duration) (probe.Prober, error) {
+	switch probeType {
+	case "ping":
+		return &probe.PingProber{
+			Host:     target,
+			Count:    3,
+			Timeout:  timeout,
+			Interval: 200 * time.Millisecond,
+			Family:   addressFamily(),
+		}, nil
+	case "http":
+		url := target
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			url = "https://" + url
+		}
+		return &probe.HTTPProber{
+			URL:     url,
+			Method:  "GET",
+			Timeout: timeout,
+			Family:  addressFamily(),
+		}, nil
+	case "dns":
+		return &probe.DigProber{
+			Host:       target,
+			RecordType: "A",
+			Timeout:    timeout,
+			Family:     addressFamily(),
+		}, nil
+	case "scan":
+		return &probe.ConnectScanner{
+			Host:        target,
+			Ports:       probe.PortSet{{Start: 1, End: 1024}},
+			Timeout:     timeout,
+			Concurrency: 100,
+			Family:      addressFamily(),
+		}, nil
+	case "trace":
+		return &probe.TraceProber{
+			Host:     target,
+			MaxHops:  30,
+			Timeout:  timeout,
+			Parallel: 16,
+			Family:   addressFamily(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported probe type %q", probeType)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(monitorCmd)
+	monitorCmd.Flags().StringArrayVar(&monitorTargets, "target", nil, "Target to monitor (repeatable)")
+	monitorCmd.Flags().StringVar(&monitorType, "type", "ping", "Default probe type (ping, http, dns, scan, trace)")
+	monitorCmd.Flags().DurationVarP(&monitorInterval, "interval", "i", 0, "Probe interval (default: monitor.interval from config)")
+	monitorCmd.Flags().Float64Var(&monitorJitter, "jitter", 0.1, "Random jitter as a fraction of the interval (0 disables)")
+	monitorCmd.Flags().DurationVar(&monitorProbeTimeout, "probe-timeout", 5*time.Second, "Timeout for each probe")
+	monitorCmd.Flags().StringVar(&monitorJSONLPath, "jsonl", "", "Append every result to this JSON Lines file")
+	monitorCmd.Flags().BoolVar(&monitorMetrics, "metrics", false, "Expose Prometheus metrics (default: metrics.enabled from config)")
+	monitorCmd.Flags().StringVar(&monitorMetricsAddr, "metrics-addr", ":9090", "Listen address for the /metrics endpoint")
+	monitorCmd.Flags().BoolVar(&monitorAnomaly, "anomaly", false, "Flag latency that deviates from each target's baseline")
+	monitorCmd.Flags().Float64Var(&monitorAnomalyZ, "anomaly-z", analyze.DefaultZThreshold, "Z-score above which latency is flagged")
+	monitorCmd.Flags().BoolVar(&monitorAnomalyHour, "anomaly-hourly", false, "Keep a separate baseline per hour of day")
+	monitorCmd.Flags().StringArrayVar(&monitorWebhooks, "webhook", nil, "POST state changes as JSON to this URL (repeatable)")
+	monitorCmd.Flags().StringVar(&monitorSlackWebhook, "slack-webhook", "", "Slack-compatible incoming webhook URL for state changes")
+	monitorCmd.Flags().StringVar(&monitorAlertExec, "alert-exec", "", "Command to run on state changes (split on spaces, no shell quoting); receives the event JSON on stdin")
+	monitorCmd.Flags().DurationVar(&monitorCooldown, "alert-cooldown", 5*time.Minute, "Minimum time between problem alerts for a target")
+	monitorCmd.Flags().IntVar(&monitorAlertAfter, "alert-after", 3, "Consecutive failures before an alert fires")
+	monitorCmd.Flags().IntVar(&monitorResolveAfter, "resolve-after", 2, "Consecutive successes before an alert resolves")
+}
@@ -0,0 +1,196 @@
+/*
+Copyright © 2026 ARCoder181105 <EMAIL ADDRESS>
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ARCoder181105/netdiag/pkg/logger"
+	"github.com/ARCoder181105/netdiag/pkg/output"
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+var (
+	mtrMaxHops  int
+	mtrTimeout  time.Duration
+	mtrInterval time.Duration
+	mtrReport   int
+)
+
+var mtrCmd = &cobra.Command{
+	Use:   "mtr <host>",
+	Short: "Continuously trace a path and track per-hop loss and latency",
+	Long: `Trace the path to a host over and over, like mtr, and keep per-hop
+statistics: probes sent, loss %, and the last, average, best and worst RTT
+with its standard deviation. Loss that starts at one hop and carries on to
+the destination shows where the problem is.
+
+By default the table updates live until Ctrl-C. --report N runs N cycles
+and prints the final table once; add --json for a report to attach to a
+ticket. "netdiag trace --continuous" does the same.
+
+Examples:
+  netdiag mtr google.com
+  netdiag mtr example.com --method tcp --port 443
+  netdiag mtr 8.8.8.8 --report 100
+  netdiag mtr 8.8.8.8 --report 100 --json > mtr-report.json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		runMTR(args[0], mtrMaxHops, mtrTimeout)
+	},
+}
+
+// runMTR runs MTR mode against host for both `netdiag mtr` and
+// `netdiag trace --continuous`, using the --interval, --report, --method,
+// --port and --parallel flags the two commands share.
+func runMTR(host string, maxHops int, timeout time.Duration) {
+	if mtrReport < 0 {
+		output.PrintError("--report must be a positive number of cycles.")
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	prober := &probe.MTRProber{
+		Host:     host,
+		MaxHops:  maxHops,
+		Timeout:  timeout,
+		Family:   addressFamily(),
+		Method:   traceMethod,
+		Port:     tracePort,
+		Parallel: traceParallel,
+		Cycles:   mtrReport,
+		Interval: mtrInterval,
+	}
+
+	// Live mode redraws the table after every cycle; a report only shows
+	// how far along it is.
+	live := mtrReport == 0 && !jsonOutput && isTerminal(os.Stdout)
+	switch {
+	case live:
+		prober.Progress = func(d probe.MTRData) {
+			fmt.Print("\033[H\033[2J")
+			printMTR(host, d)
+			fmt.Println("Press Ctrl-C to stop.")
+		}
+	case mtrReport > 0 && isTerminal(os.Stderr):
+		prober.Progress = func(d probe.MTRData) {
+			fmt.Fprintf(os.Stderr, "\r\033[KCycle %d/%d", d.Cycles, mtrReport)
+		}
+	}
+
+	result, err := prober.Probe(ctx)
+	if prober.Progress != nil && !live {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+	if err != nil {
+		result = probe.Result{
+			Target:    host,
+			ProbeType: "mtr",
+			Success:   false,
+			Severity:  probe.SeverityError,
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+		}
+	}
+
+	// ── Structured logging ────────────────────────────────────────────────
+	if result.Success && result.MTRData != nil {
+		logger.Log.Info("mtr completed",
+			"target", result.Target,
+			"cycles", result.MTRData.Cycles,
+			"hops", len(result.MTRData.Hops),
+			"latency_ms", result.Latency.Milliseconds(),
+		)
+	} else {
+		logger.Log.Error("mtr failed",
+			"target", result.Target,
+			"error", result.Message,
+		)
+	}
+	// ─────────────────────────────────────────────────────────────────────
+
+	saveResults(result)
+
+	if jsonOutput {
+		output.PrintJSON(result)
+		return
+	}
+
+	if !result.Success || result.MTRData == nil {
+		output.PrintError(result.Message)
+		return
+	}
+
+	if live {
+		// The last redraw is still on screen; drop the Ctrl-C hint.
+		fmt.Print("\033[H\033[2J")
+	}
+	printMTR(host, *result.MTRData)
+	fmt.Println()
+
+	switch result.Severity {
+	case probe.SeverityOK:
+		output.PrintSuccess(result.Message)
+	case probe.SeverityWarning:
+		output.PrintWarning(result.Message)
+	default:
+		output.PrintInfo(result.Message)
+	}
+}
+
+// printMTR prints the per-hop statistics table with mtr's columns.
+func printMTR(host string, d probe.MTRData) {
+	output.PrintInfo(fmt.Sprintf("mtr to %s (%s), %s, %d cycles", host, d.Destination, traceMethodLabel(d.Method, d.Port), d.Cycles))
+
+	headers := []string{"Hop", "Host", "Loss", "Sent", "Last", "Avg", "Best", "Worst", "StdDev"}
+	var rows [][]string
+
+	for _, hop := range d.Hops {
+		hostCell := "???"
+		last, avg, best, worst, stddev := "-", "-", "-", "-", "-"
+		if hop.Received > 0 {
+			lines := make([]string, len(hop.IPs))
+			copy(lines, hop.IPs)
+			if hop.HostName != "" {
+				lines[0] = fmt.Sprintf("%s (%s)", strings.TrimSuffix(hop.HostName, "."), hop.IPs[0])
+			}
+			hostCell = strings.Join(lines, "\n")
+			last, avg, best, worst, stddev = formatMs(hop.Last), formatMs(hop.Avg),
+				formatMs(hop.Best), formatMs(hop.Worst), formatMs(hop.StdDev)
+		}
+
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", hop.HopNumber),
+			hostCell,
+			fmt.Sprintf("%.1f%%", hop.Loss),
+			fmt.Sprintf("%d", hop.Sent),
+			last, avg, best, worst, stddev,
+		})
+	}
+
+	fmt.Println()
+	output.PrintTable(headers, rows)
+}
+
+func init() {
+	rootCmd.AddCommand(mtrCmd)
+	mtrCmd.Flags().IntVarP(&mtrMaxHops, "max-hops", "m", 30, "Maximum number of hops")
+	mtrCmd.Flags().DurationVarP(&mtrTimeout, "timeout", "t", time.Second, "Timeout per probe (e.g., 1s, 500ms)")
+	mtrCmd.Flags().DurationVarP(&mtrInterval, "interval", "i", time.Second, "Pause between cycles")
+	mtrCmd.Flags().IntVar(&mtrReport, "report", 0, "Run this many cycles, then print a report (0 = live until Ctrl-C)")
+	mtrCmd.Flags().StringVar(&traceMethod, "method", "icmp", "Probe type: icmp, udp or tcp")
+	mtrCmd.Flags().IntVar(&tracePort, "port", 0, "Destination port for udp (default 33434) and tcp (default 443) probes")
+	mtrCmd.Flags().IntVar(&traceParallel, "parallel", 16, "Number of hops probed at once (0 = all)")
+}
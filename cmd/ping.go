@@ -49,6 +49,7 @@ Examples:
 					Count:    count,
 					Timeout:  timeout,
 					Interval: interval,
+					Family:   addressFamily(),
 				}
 
 				result, err := prober.Probe(ctx)
@@ -79,6 +80,7 @@ Examples:
 			if r.Success {
 				logger.Log.Info("Ping completed",
 					"target", r.Target,
+					"mode", r.PingData.Mode,
 					"latency_ms", float64(r.Latency.Microseconds())/1000.0,
 					"loss_pct", r.PingData.PacketLoss,
 				)
@@ -91,25 +93,28 @@ Examples:
 		}
 		// -------------------------------------------------------
 
+		saveResults(results...)
+
 		if jsonOutput {
 			output.PrintJSON(results)
 			return
 		}
 
 		headers := []string{
-			"Host", "IP", "Sent", "Received", "Loss",
+			"Host", "IP", "Mode", "Sent", "Received", "Loss",
 			"Min RTT", "Avg RTT", "Max RTT", "StdDev RTT",
 			"Success", "Severity", "Message",
 		}
 		var rows [][]string
 
 		for _, result := range results {
-			ip, sent, recv := "-", "-", "-"
+			ip, mode, sent, recv := "-", "-", "-", "-"
 			loss := "100.00%"
 			min, avg, max, stddev := "-", "-", "-", "-"
 
 			if result.PingData != nil {
 				ip = result.PingData.ResolvedIP
+				mode = string(result.PingData.Mode)
 				sent = fmt.Sprintf("%d", result.PingData.PacketsSent)
 				recv = fmt.Sprintf("%d", result.PingData.PacketsRecv)
 				loss = fmt.Sprintf("%.2f%%", result.PingData.PacketLoss)
@@ -120,7 +125,7 @@ Examples:
 			}
 
 			rows = append(rows, []string{
-				result.Target, ip, sent, recv, loss,
+				result.Target, ip, mode, sent, recv, loss,
 				min, avg, max, stddev,
 				fmt.Sprintf("%t", result.Success),
 				result.Severity.String(),
@@ -130,9 +135,29 @@ Examples:
 
 		fmt.Println()
 		output.PrintTable(headers, rows)
+
+		for _, result := range results {
+			if result.PingData != nil {
+				warnPingMode(result.PingData.Mode)
+				break
+			}
+		}
 	},
 }
 
+// warnPingMode explains a fallback to TCP pings or the neighbor table,
+// whose results mean something different from ICMP ones.
+func warnPingMode(mode probe.PingMode) {
+	switch mode {
+	case probe.PingModeTCP:
+		output.PrintWarning("ICMP is unavailable (needs root, CAP_NET_RAW or net.ipv4.ping_group_range); " +
+			"used TCP connects to ports 443 and 80 instead")
+	case probe.PingModeNeighbors:
+		output.PrintWarning("ICMPv6 is unavailable (needs root, CAP_NET_RAW or net.ipv4.ping_group_range); " +
+			"listed the kernel's neighbor table without probing it")
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(pingCmd)
 
@@ -6,11 +6,13 @@ Copyright © 2026 ARCoder181105 <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/ARCoder181105/netdiag/pkg/config"
 	"github.com/ARCoder181105/netdiag/pkg/logger"
+	"github.com/ARCoder181105/netdiag/pkg/probe"
 	"github.com/spf13/cobra"
 )
 
@@ -20,6 +22,10 @@ var (
 	logFilePath string
 	logFormat   string
 	showVersion bool
+	saveOutput  bool
+	dbPath      string
+	ipv4Only    bool
+	ipv6Only    bool
 )
 
 // Version info variables
@@ -44,6 +50,9 @@ var rootCmd = &cobra.Command{
 network diagnostics, monitoring, and debugging.`,
 	// Wire Viper and Logger into PersistentPreRun
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if ipv4Only && ipv6Only {
+			return fmt.Errorf("-4 and -6 cannot be used together")
+		}
 		if err := logger.Init(logFilePath, logFormat); err != nil {
 			return fmt.Errorf("failed to initialize logger: %w", err)
 		}
@@ -63,17 +72,46 @@ network diagnostics, monitoring, and debugging.`,
 	},
 }
 
+// addressFamily returns the IP version selected with -4 or -6.
+func addressFamily() probe.Family {
+	switch {
+	case ipv4Only:
+		return probe.FamilyIPv4
+	case ipv6Only:
+		return probe.FamilyIPv6
+	default:
+		return probe.FamilyAny
+	}
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	err := rootCmd.Execute()
+	var exit *exitError
+	if errors.As(err, &exit) {
+		os.Exit(exit.code)
+	}
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// exitError asks Execute for a specific exit status once a command has
+// printed its own output, such as driftExitCode for a drifted scan.
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e *exitError) Error() string { return e.msg }
+
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&jsonOutput, "json", "j", false, "Output JSON format")
 	rootCmd.PersistentFlags().StringVarP(&logFilePath, "log-file", "l", "", "Path to the log file")
 	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format (text or json)")
+	rootCmd.PersistentFlags().BoolVarP(&ipv4Only, "ipv4", "4", false, "Use IPv4 only")
+	rootCmd.PersistentFlags().BoolVarP(&ipv6Only, "ipv6", "6", false, "Use IPv6 only")
+	rootCmd.PersistentFlags().BoolVar(&saveOutput, "save", false, "Save results to the history database")
+	rootCmd.PersistentFlags().StringVar(&dbPath, "db", "", "Path to the history database (default: database.path from config)")
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "Show version information")
 }
@@ -7,116 +7,848 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/ARCoder181105/netdiag/pkg/logger"
 	"github.com/ARCoder181105/netdiag/pkg/output"
 	"github.com/ARCoder181105/netdiag/pkg/probe"
+	"github.com/ARCoder181105/netdiag/pkg/store"
 )
 
 var (
 	ports       string
 	scanTimeout int
 	concurrency int
+	scanMethod  string
+	scanShow    string
+	detectSvc   bool
+	scanUDP     bool
+	targetsFile string
+	scanSummary bool
+	skipDown    bool
+	adaptive    bool
+	maxRate     int
+	checkpoint  string
+	resumeFrom  string
+	scanFormat  string
+	baseline    string
 )
 
+// driftExitCode is the exit status of a scan that drifted from its
+// baseline, distinct from the status 1 used for command errors.
+const driftExitCode = 3
+
 var scanCmd = &cobra.Command{
-	Use:   "scan <host>",
-	Short: "Scan for open TCP ports",
-	Long: `Scan a target host for open TCP ports using a high-concurrency worker pool.
-You can specify a single port, a list, or a range.
+	Use:   "scan <host|cidr>[,...] [more targets...]",
+	Short: "Scan for open TCP or UDP ports",
+	Long: `Scan one or more hosts for open TCP ports using a high-concurrency worker pool.
+You can specify a single port, a list, a range, a service name (ssh), a
+named set (web, db, mail, all, top100, top1000) or exclusions (1-1024,!25).
+
+Targets may be hostnames, IPs, CIDR prefixes or comma-separated lists of
+those, given as arguments or one per line in --targets-file. All hosts share
+the --concurrency budget.
 
 Examples:
   netdiag scan google.com
   netdiag scan 192.168.1.1 --ports 80,443,8000-8100
-  netdiag scan localhost -p 22 -t 2`,
-	Args: cobra.ExactArgs(1),
-	Run: func(_ *cobra.Command, args []string) {
-		host := args[0]
-
-		portList := probe.ParsePortRange(ports)
-		if len(portList) == 0 {
-			output.PrintError("No valid ports parsed. Please check your --ports flag.")
-			return
+  netdiag scan 10.0.0.5 --ports top1000
+  netdiag scan 10.0.0.5 --ports web,db,ssh,!8080
+  netdiag scan localhost -p 22 -t 2
+  sudo netdiag scan 10.0.0.5 --method syn
+  netdiag scan 10.0.0.5 -p 1-1024 --show closed,filtered
+  netdiag scan example.com -p 21,22,25,80,443,6379 --service-detect
+  netdiag scan 10.0.0.1 --udp -p 53,123,161 --show open|filtered
+  netdiag scan 10.0.0.0/24 -p 22,80,443 --skip-down --summary
+  netdiag scan --targets-file hosts.txt -p 443
+  netdiag scan 10.20.0.0/16 -p 443 --max-rate 200
+  netdiag scan 10.0.0.0/16 -p top1000 --checkpoint state.json
+  netdiag scan --resume state.json
+  netdiag scan 10.0.0.0/24 -p top100 --format nmap-xml > scan.xml
+  netdiag scan prod-db -p top1000 --baseline previous.json
+  netdiag scan prod-db -p top1000 --baseline last --save`,
+	Args: func(_ *cobra.Command, args []string) error {
+		if len(args) == 0 && targetsFile == "" && resumeFrom == "" {
+			return fmt.Errorf("requires at least one target, --targets-file or --resume")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if jsonOutput && scanFormat == "table" {
+			scanFormat = "json"
+		}
+		switch scanFormat {
+		case "table", "json", "nmap-xml", "grepable":
+		default:
+			output.PrintError(fmt.Sprintf("Unknown --format %q (use table, json, nmap-xml or grepable).", scanFormat))
+			return nil
+		}
+
+		var cp *probe.Checkpoint
+		if resumeFrom != "" {
+			var err error
+			cp, err = probe.LoadCheckpoint(resumeFrom)
+			if err != nil {
+				output.PrintError(fmt.Sprintf("Failed to load checkpoint: %v", err))
+				return nil
+			}
+			// The checkpoint supplies anything not given on the command line.
+			if len(args) == 0 && targetsFile == "" {
+				args = cp.Targets
+			}
+			if !cmd.Flags().Changed("ports") {
+				ports = cp.Ports
+			} else if ports != cp.Ports {
+				scanWarning(fmt.Sprintf("Checkpoint was saved for --ports %s; results will mix both specs.", cp.Ports))
+			}
+			if !cmd.Flags().Changed("method") && !cmd.Flags().Changed("udp") {
+				scanUDP = cp.Method == "udp"
+				if !scanUDP {
+					scanMethod = cp.Method
+				}
+			}
+		}
+
+		specs := args
+		if targetsFile != "" {
+			fileSpecs, err := readTargetsFile(targetsFile)
+			if err != nil {
+				output.PrintError(fmt.Sprintf("Failed to read targets file: %v", err))
+				return nil
+			}
+			specs = append(specs, fileSpecs...)
+		}
+
+		hosts, err := probe.ExpandTargets(specs)
+		if err != nil {
+			output.PrintError(err.Error())
+			return nil
+		}
+		if len(hosts) == 0 {
+			output.PrintError("No targets to scan.")
+			return nil
+		}
+
+		states, err := parsePortStates(scanShow)
+		if err != nil {
+			output.PrintError(err.Error())
+			return nil
+		}
+
+		portSet, err := probe.ParsePortSet(ports)
+		if err != nil {
+			output.PrintError(fmt.Sprintf("Invalid --ports: %v", err))
+			return nil
+		}
+		if portSet.Len() == 0 {
+			output.PrintError("The --ports spec selects no ports.")
+			return nil
+		}
+
+		if scanUDP {
+			if scanMethod != "connect" {
+				output.PrintError("--udp cannot be combined with --method.")
+				return nil
+			}
+			scanMethod = "udp"
+		}
+		if scanMethod != "connect" && scanMethod != "syn" && scanMethod != "udp" {
+			output.PrintError(fmt.Sprintf("Unknown scan method %q (use connect, syn or udp).", scanMethod))
+			return nil
+		}
+
+		if detectSvc && scanUDP {
+			scanWarning("Service detection only applies to TCP ports; skipping.")
+			detectSvc = false
+		}
+
+		var baselines map[string]probe.Result
+		if baseline != "" {
+			baselines, err = loadBaselines(baseline, hosts)
+			if err != nil {
+				output.PrintError(fmt.Sprintf("Failed to load baseline: %v", err))
+				return nil
+			}
+		}
+
+		if checkpoint == "" {
+			checkpoint = resumeFrom
+		}
+		if checkpoint != "" {
+			if cp == nil {
+				cp = probe.NewCheckpoint(checkpoint, specs, ports, scanMethod)
+			} else if checkpoint != resumeFrom {
+				cp.SaveTo(checkpoint)
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		timeout := time.Duration(scanTimeout) * time.Second
+		// An explicit --timeout is a fixed timeout, not a starting point,
+		// unless --adaptive is asked for as well.
+		if cmd.Flags().Changed("timeout") && !cmd.Flags().Changed("adaptive") {
+			adaptive = false
 		}
+		sem := probe.NewSemaphore(concurrency)
+		limiter := probe.NewRateLimiter(maxRate)
 
-		scanner := &probe.ConnectScanner{
+		var printer func(probe.ScanProgress)
+		if len(hosts) == 1 && scanFormat == "table" && isTerminal(os.Stderr) {
+			printer = newProgressPrinter()
+		}
+		progress := func(p probe.ScanProgress) {
+			if cp != nil {
+				cp.Record(p.Host, p.Port, p.State)
+			}
+			if printer != nil {
+				printer(p)
+			}
+		}
+
+		stopSaving := func() {}
+		if cp != nil {
+			stopSaving = saveCheckpointEvery(cp, 5*time.Second)
+		}
+
+		startTime := time.Now()
+		results := make([]probe.Result, len(hosts))
+		scanned := make([]bool, len(hosts))
+		var (
+			skipped   []string
+			skippedMu sync.Mutex
+		)
+
+		grp, gctx := errgroup.WithContext(ctx)
+		grp.SetLimit(max(concurrency, 1))
+		for i, host := range hosts {
+			grp.Go(func() error {
+				if cp != nil {
+					if r, ok := cp.Completed(host); ok {
+						results[i], scanned[i] = r, true
+						return nil
+					}
+				}
+
+				if skipDown && !probe.HostAlive(gctx, familyAddr(gctx, host), timeout) {
+					logger.Log.Info("host down, skipping", "target", host)
+					skippedMu.Lock()
+					skipped = append(skipped, host)
+					skippedMu.Unlock()
+					return nil
+				}
+
+				hostPorts := portSet
+				if cp != nil {
+					hostPorts = cp.Remaining(host, portSet)
+				}
+
+				result := scanHost(gctx, host, hostPorts, timeout, sem, limiter, progress)
+				if gctx.Err() != nil {
+					return nil // interrupted: the checkpoint holds what finished
+				}
+
+				if cp != nil {
+					if hostPorts.Len() != portSet.Len() && result.ScanData != nil {
+						cp.Merge(host, result.ScanData)
+						result.Message = fmt.Sprintf("Found %d open ports (resumed)", len(result.ScanData.OpenPorts))
+					}
+					cp.Complete(result)
+				}
+				results[i], scanned[i] = result, true
+				return nil
+			})
+		}
+		_ = grp.Wait()
+		stopSaving()
+		if printer != nil {
+			fmt.Fprint(os.Stderr, "\r\033[K")
+		}
+
+		if cp != nil {
+			if err := cp.Save(); err != nil {
+				scanWarning(fmt.Sprintf("Failed to save checkpoint: %v", err))
+			}
+		}
+		if ctx.Err() != nil {
+			if cp != nil {
+				scanWarning(fmt.Sprintf(
+					"Scan interrupted; progress saved. Resume with: netdiag scan --resume %s", checkpoint))
+			} else {
+				scanWarning("Scan interrupted; use --checkpoint to make long scans resumable.")
+			}
+		}
+
+		var done []probe.Result
+		for i, r := range results {
+			if scanned[i] {
+				done = append(done, r)
+			}
+		}
+
+		var driftErr error
+		if baseline != "" {
+			drifted := 0
+			for _, result := range done {
+				if !result.Success || result.ScanData == nil {
+					continue
+				}
+				base, ok := baselines[result.Target]
+				if !ok {
+					scanWarning(fmt.Sprintf("No baseline scan for %s; skipping comparison.", result.Target))
+					continue
+				}
+				result.ScanData.Drift = probe.CompareScans(base, result)
+				if result.ScanData.Drift.Drifted() {
+					drifted++
+				}
+			}
+			if drifted > 0 {
+				// Returned once the results below are printed; the drift
+				// report is the message, so cobra should not repeat it.
+				cmd.SilenceErrors, cmd.SilenceUsage = true, true
+				driftErr = &exitError{code: driftExitCode, msg: fmt.Sprintf("%d hosts drifted from the baseline", drifted)}
+			}
+		}
+
+		warned := false
+		for _, result := range done {
+			if !warned && result.ScanData != nil && result.ScanData.ScanMethod != scanMethod {
+				scanWarning(fmt.Sprintf(
+					"SYN scan unavailable (requires root or CAP_NET_RAW); fell back to %s scan.",
+					result.ScanData.ScanMethod,
+				))
+				warned = true
+			}
+
+			// ── Structured logging ────────────────────────────────────────────
+			if result.Success && result.ScanData != nil {
+				logger.Log.Info("scan completed",
+					"target", result.Target,
+					"total_ports", result.ScanData.TotalPorts,
+					"open_ports", result.ScanData.OpenPorts,
+					"closed_ports", len(result.ScanData.ClosedPorts),
+					"filtered_ports", len(result.ScanData.FilteredPorts),
+					"unreachable_ports", len(result.ScanData.UnreachablePorts),
+					"open_filtered_ports", len(result.ScanData.OpenFilteredPorts),
+					"scan_method", result.ScanData.ScanMethod,
+				)
+				if drift := result.ScanData.Drift; drift.Drifted() {
+					logger.Log.Warn("scan drifted from baseline",
+						"target", result.Target,
+						"baseline", drift.Baseline,
+						"opened", len(drift.Opened),
+						"closed", len(drift.Closed),
+						"service_changes", len(drift.Services),
+					)
+				}
+			} else {
+				logger.Log.Error("scan failed",
+					"target", result.Target,
+					"error", result.Message,
+				)
+			}
+			// ─────────────────────────────────────────────────────────────────
+		}
+
+		saveResults(done...)
+
+		switch scanFormat {
+		case "json":
+			if len(hosts) == 1 && len(done) == 1 {
+				output.PrintJSON(done[0])
+			} else {
+				output.PrintJSON(done)
+			}
+			return driftErr
+		case "nmap-xml", "grepable":
+			run := output.NmapRun{
+				Args:    strings.Join(os.Args, " "),
+				Version: version,
+				Start:   startTime,
+				Ports:   portSet,
+				Results: done,
+				Down:    skipped,
+			}
+			write := output.WriteNmapXML
+			if scanFormat == "grepable" {
+				write = output.WriteGrepable
+			}
+			if err := write(os.Stdout, run); err != nil {
+				output.PrintError(fmt.Sprintf("Failed to write %s output: %v", scanFormat, err))
+			}
+			return driftErr
+		}
+
+		for _, result := range done {
+			if len(hosts) > 1 {
+				fmt.Println()
+				output.PrintInfo(fmt.Sprintf("── %s ──", result.Target))
+			}
+			printScanResult(result, states)
+			if result.ScanData != nil && result.ScanData.Drift != nil {
+				printScanDrift(result.ScanData.Drift)
+			}
+		}
+
+		if len(skipped) > 0 {
+			scanWarning(fmt.Sprintf("Skipped %d of %d hosts that did not respond.", len(skipped), len(hosts)))
+		}
+
+		if scanSummary && len(done) > 1 {
+			fmt.Println()
+			printScanSummaryTable(done)
+		}
+		return driftErr
+	},
+}
+
+// scanHost runs the configured scanner (and optional service detection)
+// against one host, drawing from the shared concurrency budget.
+func scanHost(
+	ctx context.Context,
+	host string,
+	portSet probe.PortSet,
+	timeout time.Duration,
+	sem probe.Semaphore,
+	limiter *probe.RateLimiter,
+	progress func(probe.ScanProgress),
+) probe.Result {
+	var scanner probe.Prober
+	switch scanMethod {
+	case "udp":
+		scanner = &probe.UDPScanner{
+			Host:        host,
+			Ports:       portSet,
+			Timeout:     timeout,
+			Concurrency: concurrency,
+			Retries:     1,
+			Sem:         sem,
+			Limiter:     limiter,
+			Progress:    progress,
+			Family:      addressFamily(),
+		}
+	case "syn":
+		scanner = &probe.SynScanner{
 			Host:        host,
-			Ports:       portList,
-			Timeout:     time.Duration(scanTimeout) * time.Second,
+			Ports:       portSet,
+			Timeout:     timeout,
 			Concurrency: concurrency,
+			Retries:     1,
+			Sem:         sem,
+			Limiter:     limiter,
+			Progress:    progress,
+			Family:      addressFamily(),
 		}
+	default:
+		scanner = &probe.ConnectScanner{
+			Host:        host,
+			Ports:       portSet,
+			Timeout:     timeout,
+			Concurrency: concurrency,
+			Sem:         sem,
+			Progress:    progress,
+			Adaptive:    adaptive,
+			Limiter:     limiter,
+			Family:      addressFamily(),
+		}
+	}
+
+	result, err := scanner.Probe(ctx)
+	if err != nil {
+		result = probe.Result{
+			Target:    host,
+			ProbeType: "scan",
+			Success:   false,
+			Severity:  probe.SeverityError,
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+		}
+	}
+
+	if detectSvc && result.ScanData != nil && len(result.ScanData.OpenPorts) > 0 {
+		result.ScanData.Services = probe.DetectServices(ctx, familyAddr(ctx, host), result.ScanData.OpenPorts, timeout, concurrency)
+	}
+
+	return result
+}
+
+// familyAddr returns host's address in the family chosen with -4/-6, so
+// helpers that dial plain "tcp" stay within it. Without -4/-6, or if the
+// lookup fails, host is returned unchanged.
+func familyAddr(ctx context.Context, host string) string {
+	if addressFamily() == probe.FamilyAny {
+		return host
+	}
+	ip, err := probe.ResolveIP(ctx, host, addressFamily())
+	if err != nil {
+		return host
+	}
+	return ip.String()
+}
 
-		result, err := scanner.Probe(context.Background())
+// loadBaselines returns the baseline scan for each host that has one. spec
+// is either "last", meaning the most recent scan of each host in the history
+// database, or a file holding the --json output of an earlier scan: one
+// result or an array of them. A file with a single result is the baseline
+// for a single-host scan even if the targets are spelled differently.
+func loadBaselines(spec string, hosts []string) (map[string]probe.Result, error) {
+	baselines := make(map[string]probe.Result)
 
+	if spec == "last" {
+		st, err := openStore()
 		if err != nil {
-			result = probe.Result{
-				Target:    host,
-				ProbeType: "scan",
-				Success:   false,
-				Severity:  probe.SeverityError,
-				Message:   err.Error(),
-				TimeStamp: time.Now(),
-			}
-		}
-
-		// ── Structured logging ────────────────────────────────────────────────
-		if result.Success && result.ScanData != nil {
-			logger.Log.Info("scan completed",
-				"target", result.Target,
-				"total_ports", result.ScanData.TotalPorts,
-				"open_ports", result.ScanData.OpenPorts,
-				"scan_method", result.ScanData.ScanMethod,
-			)
-		} else {
-			logger.Log.Error("scan failed",
-				"target", result.Target,
-				"error", result.Message,
-			)
-		}
-		// ─────────────────────────────────────────────────────────────────────
-
-		if jsonOutput {
-			output.PrintJSON(result)
-			return
+			return nil, err
 		}
+		defer func() { _ = st.Close() }()
 
-		if result.ScanData == nil || len(result.ScanData.OpenPorts) == 0 {
-			output.PrintWarning(result.Message)
-			return
+		for _, host := range hosts {
+			found, err := st.Query(context.Background(), store.Query{Target: host, ProbeType: "scan", Limit: 1})
+			if err != nil {
+				return nil, err
+			}
+			if len(found) == 1 && found[0].ScanData != nil {
+				baselines[host] = found[0]
+			}
 		}
+		return baselines, nil
+	}
 
-		headers := []string{"Port", "Protocol", "Status"}
-		var rows [][]string
+	data, err := os.ReadFile(spec)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, p := range result.ScanData.OpenPorts {
-			rows = append(rows, []string{
-				fmt.Sprintf("%d", p),
-				"TCP",
-				"Open",
-			})
+	var results []probe.Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		var single probe.Result
+		if err := json.Unmarshal(data, &single); err != nil {
+			return nil, fmt.Errorf("%s is not netdiag scan JSON: %w", spec, err)
 		}
+		results = []probe.Result{single}
+	}
+
+	for _, r := range results {
+		if r.ScanData != nil {
+			baselines[r.Target] = r
+		}
+	}
+	if len(hosts) == 1 && len(baselines) == 1 {
+		for _, r := range baselines {
+			return map[string]probe.Result{hosts[0]: r}, nil
+		}
+	}
+	return baselines, nil
+}
+
+// printScanDrift reports the differences from the baseline scan.
+func printScanDrift(drift *probe.ScanDrift) {
+	fmt.Println()
+	when := drift.Baseline.Local().Format("2006-01-02 15:04:05")
+	if !drift.Drifted() {
+		output.PrintSuccess(fmt.Sprintf("No drift from baseline scan of %s.", when))
+		return
+	}
+
+	output.PrintWarning(fmt.Sprintf("Drift from baseline scan of %s:", when))
+	var rows [][]string
+	for _, c := range drift.Opened {
+		before := string(c.Before)
+		if before == "" {
+			before = "not scanned"
+		}
+		rows = append(rows, []string{strconv.Itoa(c.Port), "opened", before, string(c.After)})
+	}
+	for _, c := range drift.Closed {
+		rows = append(rows, []string{strconv.Itoa(c.Port), "closed", string(c.Before), string(c.After)})
+	}
+	for _, c := range drift.Services {
+		rows = append(rows, []string{strconv.Itoa(c.Port), "service changed", c.Before, c.After})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, _ := strconv.Atoi(rows[i][0])
+		b, _ := strconv.Atoi(rows[j][0])
+		return a < b
+	})
+	output.PrintTable([]string{"Port", "Change", "Before", "Now"}, rows)
+}
+
+// scanWarning prints a warning, on stderr when stdout carries
+// machine-readable output so the warning cannot corrupt it.
+func scanWarning(msg string) {
+	if scanFormat != "table" {
+		fmt.Fprintln(os.Stderr, msg)
+		return
+	}
+	output.PrintWarning(msg)
+}
+
+// saveCheckpointEvery saves cp on an interval until the returned stop
+// function is called.
+func saveCheckpointEvery(cp *probe.Checkpoint, every time.Duration) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := cp.Save(); err != nil {
+					logger.Log.Warn("checkpoint save failed", "error", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
 
-		fmt.Println()
-		output.PrintTable(headers, rows)
+// printScanResult renders one host's ports in the requested states.
+func printScanResult(result probe.Result, states []probe.PortState) {
+	if result.ScanData == nil {
+		output.PrintWarning(result.Message)
+		return
+	}
 
+	type portRow struct {
+		port  int
+		state probe.PortState
+	}
+	var shown []portRow
+	for _, state := range states {
+		for _, p := range result.ScanData.Ports(state) {
+			shown = append(shown, portRow{port: p, state: state})
+		}
+	}
+	sort.Slice(shown, func(i, j int) bool { return shown[i].port < shown[j].port })
+
+	if len(shown) == 0 {
+		output.PrintWarning(result.Message)
+		printScanSummary(result.ScanData)
+		return
+	}
+
+	headers := []string{"Port", "Protocol", "Status"}
+	if detectSvc {
+		headers = append(headers, "Service", "Version")
+	}
+
+	services := make(map[int]probe.ServiceInfo, len(result.ScanData.Services))
+	for _, svc := range result.ScanData.Services {
+		services[svc.Port] = svc
+	}
+
+	protocol := strings.ToUpper(result.ScanData.Protocol)
+	if protocol == "" {
+		protocol = "TCP"
+	}
+
+	var rows [][]string
+	for _, r := range shown {
+		row := []string{
+			fmt.Sprintf("%d", r.port),
+			protocol,
+			strings.ToUpper(string(r.state[:1])) + string(r.state[1:]),
+		}
+		if detectSvc {
+			svc, ok := services[r.port]
+			row = append(row, serviceName(svc, ok), svc.Version)
+		}
+		rows = append(rows, row)
+	}
+
+	fmt.Println()
+	output.PrintTable(headers, rows)
+
+	printScanSummary(result.ScanData)
+
+	output.PrintSuccess(result.Message)
+}
+
+// printScanSummaryTable prints one row per host with its port counts.
+func printScanSummaryTable(results []probe.Result) {
+	headers := []string{"Host", "Open", "Closed", "Filtered", "Open Ports"}
+	var rows [][]string
+
+	for _, r := range results {
+		if r.ScanData == nil {
+			rows = append(rows, []string{r.Target, "-", "-", "-", r.Message})
+			continue
+		}
+
+		d := r.ScanData
+		open := make([]string, len(d.OpenPorts))
+		for i, p := range d.OpenPorts {
+			open[i] = strconv.Itoa(p)
+		}
+
+		rows = append(rows, []string{
+			r.Target,
+			strconv.Itoa(len(d.OpenPorts)),
+			strconv.Itoa(len(d.ClosedPorts)),
+			strconv.Itoa(len(d.FilteredPorts) + len(d.OpenFilteredPorts) + len(d.UnreachablePorts)),
+			strings.Join(open, ","),
+		})
+	}
+
+	output.PrintTable(headers, rows)
+}
+
+// readTargetsFile returns the non-empty, non-comment lines of path.
+func readTargetsFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		if line = strings.TrimSpace(line); line != "" {
+			specs = append(specs, line)
+		}
+	}
+	return specs, nil
+}
+
+// parsePortStates turns the --show value into the list of states to print.
+// Open ports are always shown.
+func parsePortStates(show string) ([]probe.PortState, error) {
+	states := []probe.PortState{probe.PortOpen}
+	for _, s := range strings.Split(show, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		switch s {
+		case "", "open":
+		case "all":
+			return []probe.PortState{
+				probe.PortOpen, probe.PortClosed, probe.PortFiltered,
+				probe.PortUnreachable, probe.PortOpenFiltered,
+			}, nil
+		case string(probe.PortClosed), string(probe.PortFiltered), string(probe.PortUnreachable),
+			string(probe.PortOpenFiltered):
+			if !slices.Contains(states, probe.PortState(s)) {
+				states = append(states, probe.PortState(s))
+			}
+		default:
+			return nil, fmt.Errorf("unknown port state %q in --show (use closed, filtered, unreachable, open|filtered or all)", s)
+		}
+	}
+	return states, nil
+}
+
+// serviceName formats a detected service for the table, marking services
+// wrapped in TLS other than HTTPS.
+func serviceName(svc probe.ServiceInfo, ok bool) string {
+	switch {
+	case !ok:
+		return ""
+	case svc.TLS && svc.Service != "https" && svc.Service != "ssl/unknown":
+		return svc.Service + "/tls"
+	default:
+		return svc.Service
+	}
+}
+
+func printScanSummary(d *probe.ScanData) {
+	if d.Protocol == "udp" {
 		output.PrintInfo(fmt.Sprintf(
-			"Scanned %d ports (%d ports/ms) using %s method.",
-			result.ScanData.TotalPorts,
-			result.ScanData.ScanRateMs,
-			result.ScanData.ScanMethod,
+			"Scanned %d UDP ports in %s (%.0f ports/s): %d open, %d closed, %d open|filtered, %d unreachable.",
+			d.TotalPorts,
+			d.Elapsed.Round(time.Millisecond),
+			d.PortsPerSec,
+			len(d.OpenPorts),
+			len(d.ClosedPorts),
+			len(d.OpenFilteredPorts),
+			len(d.UnreachablePorts),
 		))
+		return
+	}
 
-		output.PrintSuccess(result.Message)
-	},
+	output.PrintInfo(fmt.Sprintf(
+		"Scanned %d ports in %s (%.0f ports/s) using %s method: "+
+			"%d open, %d closed, %d filtered, %d unreachable (%d timeouts, %d refused).",
+		d.TotalPorts,
+		d.Elapsed.Round(time.Millisecond),
+		d.PortsPerSec,
+		d.ScanMethod,
+		len(d.OpenPorts),
+		len(d.ClosedPorts),
+		len(d.FilteredPorts),
+		len(d.UnreachablePorts),
+		d.Timeouts,
+		d.Refused,
+	))
+
+	if d.AdaptiveTimeout > 0 {
+		output.PrintInfo(fmt.Sprintf(
+			"Adaptive timing: RTT %s, timeout %s, final concurrency %d.",
+			d.RTT.Round(time.Microsecond),
+			d.AdaptiveTimeout.Round(time.Millisecond),
+			d.Concurrency,
+		))
+	}
+}
+
+// newProgressPrinter returns a Progress callback that redraws a single
+// status line on stderr at most ten times a second.
+func newProgressPrinter() func(probe.ScanProgress) {
+	var last time.Time
+	return func(p probe.ScanProgress) {
+		if p.Done != p.Total && time.Since(last) < 100*time.Millisecond {
+			return
+		}
+		last = time.Now()
+		fmt.Fprintf(os.Stderr, "\r\033[KScanning %s: %d/%d ports (%.1f%%), %d open, %.0f ports/s",
+			p.Host, p.Done, p.Total, 100*float64(p.Done)/float64(p.Total), p.Open, p.PortsPerSec())
+	}
+}
+
+// isTerminal reports whether f is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
 }
 
 func init() {
 	rootCmd.AddCommand(scanCmd)
-	scanCmd.Flags().IntVarP(&scanTimeout, "timeout", "t", 1, "Timeout in seconds")
-	scanCmd.Flags().StringVarP(&ports, "ports", "p", "1-1024", "The range to scan")
-	scanCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 100, "Number of concurrent ports to scan")
+	scanCmd.Flags().IntVarP(&scanTimeout, "timeout", "t", 1, "Timeout in seconds; fixed unless --adaptive is also given")
+	scanCmd.Flags().StringVarP(&ports, "ports", "p", "1-1024",
+		"Ports to scan: numbers, ranges, service names, sets (web, db, mail, all, topN) and !exclusions")
+	scanCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 100, "Number of concurrent ports to scan, shared across all hosts")
+	scanCmd.Flags().StringVarP(&scanMethod, "method", "m", "connect", "Scan method: connect or syn (raw socket, needs root)")
+	scanCmd.Flags().BoolVarP(&scanUDP, "udp", "u", false, "Scan UDP ports instead of TCP")
+	scanCmd.Flags().BoolVar(&detectSvc, "service-detect", false, "Identify services and versions on open ports from banners and HTTP/TLS probes")
+	scanCmd.Flags().StringVar(&targetsFile, "targets-file", "", "Read targets (hosts, IPs, CIDRs) from a file, one per line")
+	scanCmd.Flags().BoolVar(&scanSummary, "summary", false, "Print a combined per-host summary table when scanning several hosts")
+	scanCmd.Flags().BoolVar(&skipDown, "skip-down", false, "Check each host is up (TCP 80/443/22/445) and skip hosts that do not respond")
+	scanCmd.Flags().BoolVar(&adaptive, "adaptive", true, "Derive per-host timeouts from measured RTT and back off concurrency when timeouts spike (off when --timeout is set)")
+	scanCmd.Flags().IntVar(&maxRate, "max-rate", 0, "Maximum probes per second across all hosts (0 = unlimited; UDP defaults to 100)")
+	scanCmd.Flags().StringVar(&checkpoint, "checkpoint", "", "Periodically save progress to this file so the scan can be resumed")
+	scanCmd.Flags().StringVar(&resumeFrom, "resume", "", "Resume a scan from a checkpoint file, skipping finished hosts and ports")
+	scanCmd.Flags().StringVar(&scanFormat, "format", "table", "Output format: table, json, nmap-xml or grepable")
+	scanCmd.Flags().StringVar(&baseline, "baseline", "",
+		`Compare with an earlier scan: a --json output file, or "last" for each host's last stored scan; exits 3 on drift`)
+	scanCmd.Flags().StringVar(&scanShow, "show", "", "Also list ports in these states: closed, filtered, unreachable, open|filtered or all")
 }
@@ -58,6 +58,7 @@ Examples:
 				TimeStamp: time.Now(),
 			}
 			logger.Log.Error("speedtest failed", "error", err)
+			saveResults(result)
 
 			if jsonOutput {
 				output.PrintJSON(result)
@@ -83,6 +84,8 @@ Examples:
 			)
 		}
 
+		saveResults(result)
+
 		// JSON mode
 		if jsonOutput {
 			output.PrintJSON(result)
@@ -0,0 +1,56 @@
+/*
+Copyright © 2026 ARCoder181105 <EMAIL ADDRESS>
+*/
+
+// Package cmd implements the CLI commands.
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ARCoder181105/netdiag/pkg/config"
+	"github.com/ARCoder181105/netdiag/pkg/logger"
+	"github.com/ARCoder181105/netdiag/pkg/output"
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+	"github.com/ARCoder181105/netdiag/pkg/store"
+)
+
+// openStore opens the history database at --db, or database.path from the
+// config file when the flag is not set.
+func openStore() (store.Store, error) {
+	path := dbPath
+	if path == "" {
+		path = config.AppConfig.Database.Path
+	}
+
+	path, err := config.ExpandPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve database path: %w", err)
+	}
+
+	return store.Open(path)
+}
+
+// saveResults persists results to the history database when --save is set.
+// Failures are reported but never abort the command.
+func saveResults(results ...probe.Result) {
+	if !saveOutput {
+		return
+	}
+
+	st, err := openStore()
+	if err != nil {
+		logger.Log.Error("failed to open history database", "error", err)
+		output.PrintWarning(fmt.Sprintf("Results not saved: %v", err))
+		return
+	}
+	defer func() { _ = st.Close() }()
+
+	for _, r := range results {
+		if err := st.SaveResult(context.Background(), r); err != nil {
+			logger.Log.Error("failed to save result", "target", r.Target, "error", err)
+			output.PrintWarning(fmt.Sprintf("Result for %s not saved: %v", r.Target, err))
+		}
+	}
+}
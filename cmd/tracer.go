@@ -8,6 +8,8 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -18,8 +20,13 @@ import (
 )
 
 var (
-	maxHops      int
-	traceTimeout time.Duration
+	maxHops         int
+	traceQueries    int
+	traceTimeout    time.Duration
+	traceContinuous bool
+	traceMethod     string
+	tracePort       int
+	traceParallel   int
 )
 
 var traceCmd = &cobra.Command{
@@ -28,16 +35,51 @@ var traceCmd = &cobra.Command{
 	Long: `Trace the network path to a destination host by sending ICMP packets
 with increasing TTL values. Shows each hop (router) along the path.
 
+Each hop is probed --queries times (3 by default). A hop lists every
+address that answered, so load-balanced paths show several, with the RTT
+of each probe, its loss and min/avg/max RTT. A "*" is a probe that got no
+reply; !H, !N, !P, !X and similar mark destination unreachable replies, as
+in traceroute.
+
+--method picks the probe type: ICMP echo (the default), UDP datagrams to
+port 33434, or TCP SYNs to port 443, for paths whose firewalls drop ICMP
+echo. --port changes the UDP or TCP port. Like Paris traceroute, every
+probe keeps the same addresses and ports, so load balancers that hash
+flows send them all down one path instead of inventing hops. A TCP reply
+from the destination shows the port as [open] or [closed].
+
+Up to --parallel hops (16 by default) are probed at once, and the trace
+stops at the first hop that reaches the destination, so silent routers
+cost one timeout per window rather than one per hop. --parallel 1 probes
+one hop at a time.
+
+--continuous switches to MTR mode (see "netdiag mtr"): the path is traced
+again and again with live per-hop loss and RTT statistics.
+
 Example:
   netdiag trace google.com
-  netdiag trace 8.8.8.8`,
+  netdiag trace 8.8.8.8 -q 5
+  netdiag trace example.com --method tcp --port 443
+  netdiag trace example.com --method udp
+  netdiag trace 8.8.8.8 --continuous
+  netdiag trace 8.8.8.8 --continuous --report 50`,
 	Args: cobra.ExactArgs(1),
 	Run: func(_ *cobra.Command, args []string) {
 
+		if traceContinuous || mtrReport > 0 {
+			runMTR(args[0], maxHops, traceTimeout)
+			return
+		}
+
 		prober := &probe.TraceProber{
-			Host:    args[0],
-			MaxHops: maxHops,
-			Timeout: traceTimeout,
+			Host:     args[0],
+			MaxHops:  maxHops,
+			Queries:  traceQueries,
+			Timeout:  traceTimeout,
+			Method:   traceMethod,
+			Port:     tracePort,
+			Parallel: traceParallel,
+			Family:   addressFamily(),
 		}
 
 		result, err := prober.Probe(context.Background())
@@ -62,6 +104,8 @@ Example:
 		}
 		// ─────────────────────────────────────────────────────────────────────
 
+		saveResults(result)
+
 		if jsonOutput {
 			output.PrintJSON(result)
 			return
@@ -72,31 +116,25 @@ Example:
 			return
 		}
 
-		headers := []string{"Hop", "IP Address", "Hostname", "RTT (ms)"}
+		output.PrintInfo(fmt.Sprintf("traceroute to %s (%s), %d hops max, %d probes per hop, %s",
+			result.Target, result.TraceData.Destination, maxHops, traceQueries, traceMethodLabel(result.TraceData.Method, result.TraceData.Port)))
+
+		headers := []string{"Hop", "Host", "Probes", "Loss", "Min (ms)", "Avg (ms)", "Max (ms)"}
 		var rows [][]string
 
 		for _, hop := range result.TraceData.Hops {
-			rttMs := "*"
+			host, probes := traceHopCells(hop)
+			min, avg, max := "-", "-", "-"
 			if !hop.Timeout {
-				rttMs = fmt.Sprintf("%.2f",
-					float64(hop.RTT.Microseconds())/1000.0)
-			}
-
-			ip := hop.IP
-			if hop.Timeout {
-				ip = "*"
-			}
-
-			hostname := hop.HostName
-			if hop.Timeout || hostname == "" {
-				hostname = "*"
+				min, avg, max = formatMs(hop.MinRTT), formatMs(hop.AvgRTT), formatMs(hop.MaxRTT)
 			}
 
 			rows = append(rows, []string{
 				fmt.Sprintf("%d", hop.HopNumber),
-				ip,
-				hostname,
-				rttMs,
+				host,
+				probes,
+				fmt.Sprintf("%.0f%%", hop.Loss),
+				min, avg, max,
 			})
 		}
 
@@ -117,9 +155,117 @@ Example:
 	},
 }
 
+// traceHopCells renders a hop the way traceroute prints it: each address
+// that answered as "name (ip)" on its own line, next to the RTTs of the
+// probes it answered. Probes that timed out show as "*" on the first line.
+func traceHopCells(hop probe.TraceHop) (host, probes string) {
+	if hop.Timeout {
+		stars := make([]string, max(len(hop.Probes), 1))
+		for i := range stars {
+			stars[i] = "*"
+		}
+		return "*", strings.Join(stars, "  ")
+	}
+
+	// Results saved before per-probe data was recorded hold one reply.
+	sent := hop.Probes
+	if len(sent) == 0 {
+		sent = []probe.TraceProbe{{IP: hop.IP, HostName: hop.HostName, RTT: hop.RTT, ICMPType: hop.ICMPType, ICMPCode: hop.ICMPCode}}
+	}
+	ips := hop.IPs
+	if len(ips) == 0 {
+		ips = []string{hop.IP}
+	}
+
+	var hosts, times []string
+	for i, ip := range ips {
+		var name string
+		var state probe.PortState
+		var rtts []string
+		for _, p := range sent {
+			switch {
+			case p.Timeout:
+				if i == 0 {
+					rtts = append(rtts, "*")
+				}
+			case p.IP == ip:
+				if p.HostName != "" {
+					name = p.HostName
+				}
+				if p.PortState != "" {
+					state = p.PortState
+				}
+				rtts = append(rtts, formatMs(p.RTT)+" ms"+unreachableFlag(p))
+			}
+		}
+
+		label := ip
+		if name != "" {
+			label = fmt.Sprintf("%s (%s)", strings.TrimSuffix(name, "."), ip)
+		}
+		if state != "" {
+			// The destination answered a TCP probe; say how.
+			label += fmt.Sprintf(" [%s]", state)
+		}
+		hosts = append(hosts, label)
+		times = append(times, strings.Join(rtts, "  "))
+	}
+
+	return strings.Join(hosts, "\n"), strings.Join(times, "\n")
+}
+
+// unreachableFlag returns traceroute's annotation for a destination
+// unreachable reply, such as " !H" for host unreachable. Port unreachable
+// is how a UDP probe arrives, so it is not flagged.
+func unreachableFlag(p probe.TraceProbe) string {
+	ip := net.ParseIP(p.IP)
+	if ip == nil {
+		return ""
+	}
+
+	var flags map[int]string
+	switch {
+	case ip.To4() != nil && p.ICMPType == 3:
+		flags = map[int]string{0: "!N", 1: "!H", 2: "!P", 3: "", 4: "!F", 5: "!S", 13: "!X", 14: "!V", 15: "!C"}
+	case ip.To4() == nil && p.ICMPType == 1:
+		flags = map[int]string{0: "!N", 1: "!X", 3: "!H", 4: ""}
+	default:
+		return ""
+	}
+
+	flag, ok := flags[p.ICMPCode]
+	if !ok {
+		flag = fmt.Sprintf("!<%d>", p.ICMPCode)
+	}
+	if flag == "" {
+		return ""
+	}
+	return " " + flag
+}
+
+// traceMethodLabel describes the probes of a trace, e.g. "tcp port 443".
+func traceMethodLabel(method string, port int) string {
+	if port == 0 {
+		return method
+	}
+	return fmt.Sprintf("%s port %d", method, port)
+}
+
+// formatMs renders a duration as milliseconds with traceroute's precision.
+func formatMs(d time.Duration) string {
+	return fmt.Sprintf("%.3f", float64(d.Microseconds())/1000.0)
+}
+
 func init() {
 	rootCmd.AddCommand(traceCmd)
 	traceCmd.Flags().IntVarP(&maxHops, "max-hops", "m", 30, "Maximum number of hops")
+	traceCmd.Flags().IntVarP(&traceQueries, "queries", "q", 3, "Number of probes per hop")
+	traceCmd.Flags().StringVar(&traceMethod, "method", "icmp", "Probe type: icmp, udp or tcp")
+	traceCmd.Flags().IntVar(&tracePort, "port", 0, "Destination port for udp (default 33434) and tcp (default 443) probes")
+	traceCmd.Flags().IntVar(&traceParallel, "parallel", 16, "Number of hops probed at once (0 = all)")
+	traceCmd.Flags().BoolVar(&traceContinuous, "continuous", false, "Trace continuously in MTR mode")
+	traceCmd.Flags().DurationVarP(&mtrInterval, "interval", "i", time.Second, "Pause between cycles with --continuous")
+	traceCmd.Flags().IntVar(&mtrReport, "report", 0, "With --continuous, run this many cycles and print a report")
 	traceCmd.Flags().DurationVarP(
 		&traceTimeout,
 		"timeout",
@@ -26,6 +26,7 @@ including the registrar, creation date, and expiration date.`,
 
 		prober := &probe.WhoisProber{
 			Domain: args[0],
+			Family: addressFamily(),
 		}
 
 		output.PrintInfo(fmt.Sprintf("Querying WHOIS for %s...", args[0]))
@@ -51,6 +52,8 @@ including the registrar, creation date, and expiration date.`,
 		}
 		// ─────────────────────────────────────────────────────────────────────
 
+		saveResults(result)
+
 		if jsonOutput {
 			output.PrintJSON(result)
 			return
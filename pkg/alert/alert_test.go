@@ -0,0 +1,259 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+func result(ts time.Time, sev probe.Severity) probe.Result {
+	return probe.Result{
+		TimeStamp: ts,
+		Target:    "10.0.0.1",
+		ProbeType: "ping",
+		Success:   sev != probe.SeverityError,
+		Severity:  sev,
+		Message:   sev.String(),
+	}
+}
+
+func TestManagerHysteresis(t *testing.T) {
+	m := &Manager{FailThreshold: 3, ResolveThreshold: 2}
+	now := time.Now()
+
+	steps := []struct {
+		sev       probe.Severity
+		wantFire  bool
+		wantState string
+	}{
+		{probe.SeverityOK, false, ""},
+		{probe.SeverityError, false, ""},
+		{probe.SeverityOK, false, ""}, // streak broken
+		{probe.SeverityError, false, ""},
+		{probe.SeverityError, false, ""},
+		{probe.SeverityError, true, "Error"},
+		{probe.SeverityError, false, ""}, // no repeat while state is unchanged
+		{probe.SeverityOK, false, ""},
+		{probe.SeverityOK, true, "OK"},
+	}
+
+	for i, s := range steps {
+		e, fired := m.Observe(result(now.Add(time.Duration(i)*time.Minute), s.sev))
+		if fired != s.wantFire {
+			t.Fatalf("step %d: fired = %v, want %v", i, fired, s.wantFire)
+		}
+		if fired && e.State != s.wantState {
+			t.Errorf("step %d: state = %s, want %s", i, e.State, s.wantState)
+		}
+		if fired && s.wantState == "OK" && (!e.Resolved || e.Previous != "Error") {
+			t.Errorf("step %d: expected resolved event from Error, got %+v", i, e)
+		}
+	}
+}
+
+func TestManagerCooldown(t *testing.T) {
+	m := &Manager{Cooldown: 10 * time.Minute}
+	now := time.Now()
+
+	if _, fired := m.Observe(result(now, probe.SeverityWarning)); !fired {
+		t.Fatal("first warning should fire")
+	}
+	// Escalation inside the cooldown is held back.
+	if _, fired := m.Observe(result(now.Add(time.Minute), probe.SeverityError)); fired {
+		t.Error("escalation inside cooldown should be held back")
+	}
+	// The announced problem still resolves.
+	if e, fired := m.Observe(result(now.Add(2*time.Minute), probe.SeverityOK)); !fired || !e.Resolved {
+		t.Error("resolution should always be sent")
+	}
+	// Flapping back inside the cooldown is held back, and so is its recovery.
+	if _, fired := m.Observe(result(now.Add(3*time.Minute), probe.SeverityError)); fired {
+		t.Error("flap inside cooldown should be held back")
+	}
+	if _, fired := m.Observe(result(now.Add(4*time.Minute), probe.SeverityOK)); fired {
+		t.Error("recovery of a held-back problem should not be sent")
+	}
+
+	// An outage that starts inside the cooldown fires once it ends...
+	if _, fired := m.Observe(result(now.Add(5*time.Minute), probe.SeverityError)); fired {
+		t.Error("outage inside cooldown should be held back")
+	}
+	e, fired := m.Observe(result(now.Add(11*time.Minute), probe.SeverityError))
+	if !fired || e.State != "Error" || e.Previous != "OK" {
+		t.Fatalf("outage still present after cooldown should fire late, got fired=%v %+v", fired, e)
+	}
+	if _, fired := m.Observe(result(now.Add(12*time.Minute), probe.SeverityError)); fired {
+		t.Error("late alert should fire once")
+	}
+	// ...and its recovery is announced.
+	if e, fired := m.Observe(result(now.Add(13*time.Minute), probe.SeverityOK)); !fired || !e.Resolved {
+		t.Error("recovery of a late alert should be sent")
+	}
+}
+
+func TestManagerAnomalyIsWarning(t *testing.T) {
+	m := &Manager{}
+	r := result(time.Now(), probe.SeverityOK)
+	r.Anomaly = &probe.AnomalyData{ZScore: 9}
+
+	e, fired := m.Observe(r)
+	if !fired || e.State != "Warning" {
+		t.Errorf("anomaly should fire a Warning, got fired=%v state=%q", fired, e.State)
+	}
+}
+
+func TestWebhookAndSlackAlerters(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies = map[string][]byte{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[r.URL.Path] = body
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	m := &Manager{
+		Alerters: []Alerter{
+			&WebhookAlerter{URL: srv.URL + "/hook"},
+			&SlackAlerter{WebhookURL: srv.URL + "/slack"},
+		},
+	}
+
+	if err := m.Write(context.Background(), result(time.Now(), probe.SeverityError)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	// Delivery happens in the background; Close waits for it.
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var e Event
+	if err := json.Unmarshal(bodies["/hook"], &e); err != nil {
+		t.Fatalf("webhook body is not an Event: %v", err)
+	}
+	if e.Target != "10.0.0.1" || e.State != "Error" || e.Previous != "OK" || e.Resolved {
+		t.Errorf("unexpected webhook event: %+v", e)
+	}
+
+	var slack slackPayload
+	if err := json.Unmarshal(bodies["/slack"], &slack); err != nil {
+		t.Fatalf("slack body: %v", err)
+	}
+	if !strings.Contains(slack.Text, "10.0.0.1") || slack.Attachments[0].Color != "danger" {
+		t.Errorf("unexpected slack payload: %+v", slack)
+	}
+}
+
+// blockingAlerter stalls every delivery until release is closed.
+type blockingAlerter struct {
+	release chan struct{}
+	sent    chan Event
+}
+
+func (a *blockingAlerter) Alert(ctx context.Context, e Event) error {
+	select {
+	case <-a.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	a.sent <- e
+	return nil
+}
+
+func TestManagerWriteDoesNotWaitForAlerters(t *testing.T) {
+	a := &blockingAlerter{release: make(chan struct{}), sent: make(chan Event, 2)}
+	m := &Manager{Alerters: []Alerter{a}, Timeout: time.Minute}
+
+	now := time.Now()
+	written := make(chan error, 1)
+	go func() {
+		if err := m.Write(context.Background(), result(now, probe.SeverityError)); err != nil {
+			written <- err
+			return
+		}
+		written <- m.Write(context.Background(), result(now.Add(time.Second), probe.SeverityOK))
+	}()
+
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write() blocked on a stalled alerter")
+	}
+
+	close(a.release)
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	close(a.sent)
+
+	var states []string
+	for e := range a.sent {
+		states = append(states, e.State)
+	}
+	if want := []string{"Error", "OK"}; !slices.Equal(states, want) {
+		t.Errorf("delivered states = %v, want %v in order", states, want)
+	}
+	if err := m.Write(context.Background(), result(now.Add(2*time.Second), probe.SeverityError)); err == nil {
+		t.Error("Write() after Close should report the dropped alert")
+	}
+}
+
+func TestWebhookAlerterHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	a := &WebhookAlerter{URL: srv.URL}
+	if err := a.Alert(context.Background(), Event{}); err == nil {
+		t.Error("expected an error for HTTP 500")
+	}
+}
+
+func TestExecAlerter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	out := filepath.Join(t.TempDir(), "event.json")
+	a := &ExecAlerter{
+		Command: "sh",
+		Args:    []string{"-c", `cat > "$OUT" && test "$NETDIAG_STATE" = Error`},
+	}
+	t.Setenv("OUT", out)
+
+	e := Event{Target: "10.0.0.1", State: "Error", Previous: "OK"}
+	if err := a.Alert(context.Background(), e); err != nil {
+		t.Fatalf("Alert() error = %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("script did not receive stdin: %v", err)
+	}
+	var got Event
+	if err := json.Unmarshal(data, &got); err != nil || got.Target != "10.0.0.1" {
+		t.Errorf("stdin = %s, want event JSON", data)
+	}
+}
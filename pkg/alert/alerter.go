@@ -0,0 +1,39 @@
+// Package alert turns changes in probe state into notifications.
+package alert
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+// Event is a confirmed change in a target's state.
+type Event struct {
+	Target    string `json:"target"`
+	ProbeType string `json:"probe_type"`
+	// State and Previous are probe.Severity names: "OK", "Warning" or "Error".
+	State    string `json:"state"`
+	Previous string `json:"previous"`
+	// Resolved is set when the target has recovered to OK.
+	Resolved bool         `json:"resolved"`
+	Time     time.Time    `json:"time"`
+	Message  string       `json:"message"`
+	Result   probe.Result `json:"result"`
+}
+
+// Summary returns a one-line, human-readable description of the event.
+func (e Event) Summary() string {
+	if e.Resolved {
+		return fmt.Sprintf("RESOLVED: %s %s is OK again (was %s): %s",
+			e.ProbeType, e.Target, e.Previous, e.Message)
+	}
+	return fmt.Sprintf("%s: %s %s changed from %s: %s",
+		e.State, e.ProbeType, e.Target, e.Previous, e.Message)
+}
+
+// Alerter delivers an event to a notification channel.
+type Alerter interface {
+	Alert(ctx context.Context, e Event) error
+}
@@ -0,0 +1,49 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ExecAlerter runs a local command for every event, writing the event as
+// JSON to its stdin. The target, state and resolution are also exported as
+// NETDIAG_TARGET, NETDIAG_PROBE_TYPE, NETDIAG_STATE, NETDIAG_PREVIOUS and
+// NETDIAG_RESOLVED for simple shell scripts.
+type ExecAlerter struct {
+	Command string
+	Args    []string
+}
+
+func (x *ExecAlerter) Alert(ctx context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, x.Command, x.Args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"NETDIAG_TARGET="+e.Target,
+		"NETDIAG_PROBE_TYPE="+e.ProbeType,
+		"NETDIAG_STATE="+e.State,
+		"NETDIAG_PREVIOUS="+e.Previous,
+		fmt.Sprintf("NETDIAG_RESOLVED=%t", e.Resolved),
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return fmt.Errorf("alert command %q failed: %w: %s", x.Command, err, msg)
+		}
+		return fmt.Errorf("alert command %q failed: %w", x.Command, err)
+	}
+	return nil
+}
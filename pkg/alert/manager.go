@@ -0,0 +1,250 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+// Manager tracks the state of every target and notifies its alerters when
+// the state changes. It implements monitor.Sink.
+//
+// A change only counts once it has been seen FailThreshold times in a row
+// (or ResolveThreshold times for a recovery), so a single dropped probe
+// does not page anyone. After a problem alert is sent, further problem
+// alerts for the same target (escalations or a flapping host) are held
+// back for Cooldown; a problem still present when the cooldown ends fires
+// then. The recovery notification for an announced problem is always
+// sent; problems that recover while held back are never announced.
+//
+// State changes are tracked synchronously in Write, but notifications are
+// queued and delivered in order by a single background worker, so a slow
+// or hanging alerter cannot stall the monitor's other sinks. Close waits
+// for queued notifications to go out.
+type Manager struct {
+	Alerters []Alerter
+
+	// FailThreshold is the number of consecutive non-OK results needed
+	// before a problem fires. Defaults to 1.
+	FailThreshold int
+	// ResolveThreshold is the number of consecutive OK results needed
+	// before a problem resolves. Defaults to 1.
+	ResolveThreshold int
+	Cooldown         time.Duration
+	// Timeout bounds each alerter call. Defaults to 10s.
+	Timeout time.Duration
+	Logger  *slog.Logger
+
+	mu     sync.Mutex
+	states map[stateKey]*targetState
+	closed bool
+
+	start sync.Once
+	queue chan Event
+	done  chan struct{}
+}
+
+// alertQueueSize bounds the notifications waiting for delivery. Beyond it,
+// new ones are dropped rather than blocking the monitor.
+const alertQueueSize = 64
+
+type stateKey struct {
+	target    string
+	probeType string
+}
+
+type targetState struct {
+	current   probe.Severity
+	pending   probe.Severity
+	streak    int
+	firing    bool // a problem alert has been sent and not yet resolved
+	lastAlert time.Time
+}
+
+// Write implements monitor.Sink. It queues the notification for a state
+// change and returns without waiting for the alerters.
+func (m *Manager) Write(_ context.Context, r probe.Result) error {
+	e, ok := m.Observe(r)
+	if !ok {
+		return nil
+	}
+	return m.enqueue(e)
+}
+
+// enqueue hands e to the delivery worker, starting it on first use.
+func (m *Manager) enqueue(e Event) error {
+	m.start.Do(func() {
+		m.queue = make(chan Event, alertQueueSize)
+		m.done = make(chan struct{})
+		go m.deliver()
+	})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return fmt.Errorf("alert manager closed; dropped %s alert for %s", e.State, e.Target)
+	}
+	select {
+	case m.queue <- e:
+		return nil
+	default:
+		return fmt.Errorf("alert queue full; dropped %s alert for %s", e.State, e.Target)
+	}
+}
+
+// deliver sends queued events one at a time until Close.
+func (m *Manager) deliver() {
+	defer close(m.done)
+	for e := range m.queue {
+		if err := m.notify(context.Background(), e); err != nil {
+			m.logger().Error("alert delivery failed", "target", e.Target, "state", e.State, "error", err)
+		}
+	}
+}
+
+// Close stops accepting results and waits until every queued notification
+// has been delivered.
+func (m *Manager) Close() error {
+	m.start.Do(func() {})
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	if m.queue != nil {
+		close(m.queue)
+	}
+	m.mu.Unlock()
+
+	if m.done != nil {
+		<-m.done
+	}
+	return nil
+}
+
+// Observe feeds a result into the state machine and returns the event to
+// send, if any.
+func (m *Manager) Observe(r probe.Result) (Event, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.states == nil {
+		m.states = make(map[stateKey]*targetState)
+	}
+
+	key := stateKey{r.Target, r.ProbeType}
+	st, ok := m.states[key]
+	if !ok {
+		st = &targetState{current: probe.SeverityOK}
+		m.states[key] = st
+	}
+
+	observed := stateOf(r)
+	if observed == st.current {
+		st.streak = 0
+		return Event{}, false
+	}
+
+	if observed == st.pending && st.streak > 0 {
+		st.streak++
+	} else {
+		st.pending = observed
+		st.streak = 1
+	}
+
+	threshold := max(m.FailThreshold, 1)
+	if observed == probe.SeverityOK {
+		threshold = max(m.ResolveThreshold, 1)
+	}
+	if st.streak < threshold {
+		return Event{}, false
+	}
+
+	e := Event{
+		Target:    r.Target,
+		ProbeType: r.ProbeType,
+		State:     observed.String(),
+		Previous:  st.current.String(),
+		Resolved:  observed == probe.SeverityOK,
+		Time:      r.TimeStamp,
+		Message:   r.Message,
+		Result:    r,
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	// A problem held back by the cooldown is not committed, so the streak
+	// carries on and the next result after the cooldown fires it.
+	if !e.Resolved && m.Cooldown > 0 && !st.lastAlert.IsZero() && e.Time.Sub(st.lastAlert) < m.Cooldown {
+		return Event{}, false
+	}
+
+	st.current = observed
+	st.streak = 0
+
+	if e.Resolved {
+		if !st.firing {
+			return Event{}, false
+		}
+		st.firing = false
+		return e, true
+	}
+
+	st.firing = true
+	st.lastAlert = e.Time
+	return e, true
+}
+
+func (m *Manager) logger() *slog.Logger {
+	if m.Logger == nil {
+		return slog.Default()
+	}
+	return m.Logger
+}
+
+// notify calls every alerter with e, each bounded by Timeout.
+func (m *Manager) notify(ctx context.Context, e Event) error {
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	m.logger().Info("alert",
+		"target", e.Target,
+		"probe_type", e.ProbeType,
+		"state", e.State,
+		"previous", e.Previous,
+		"resolved", e.Resolved,
+	)
+
+	var errs []error
+	for _, a := range m.Alerters {
+		actx, cancel := context.WithTimeout(ctx, timeout)
+		if err := a.Alert(actx, e); err != nil {
+			errs = append(errs, err)
+		}
+		cancel()
+	}
+	return errors.Join(errs...)
+}
+
+// stateOf reduces a result to OK, Warning or Error. A latency anomaly on an
+// otherwise healthy result counts as a warning.
+func stateOf(r probe.Result) probe.Severity {
+	switch {
+	case !r.Success || r.Severity == probe.SeverityError || r.Severity == probe.SeverityUnknown:
+		return probe.SeverityError
+	case r.Severity == probe.SeverityWarning || r.Anomaly != nil:
+		return probe.SeverityWarning
+	default:
+		return probe.SeverityOK
+	}
+}
@@ -0,0 +1,97 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WebhookAlerter POSTs every event as a JSON document to URL.
+type WebhookAlerter struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *WebhookAlerter) Alert(ctx context.Context, e Event) error {
+	return postJSON(ctx, w.Client, w.URL, e)
+}
+
+// SlackAlerter posts events to a Slack-compatible incoming webhook
+// (Slack, Mattermost, Rocket.Chat, ...).
+type SlackAlerter struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+type slackPayload struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func (s *SlackAlerter) Alert(ctx context.Context, e Event) error {
+	color := "danger"
+	switch {
+	case e.Resolved:
+		color = "good"
+	case e.State == "Warning":
+		color = "warning"
+	}
+
+	payload := slackPayload{
+		Text: e.Summary(),
+		Attachments: []slackAttachment{{
+			Color: color,
+			Fields: []slackField{
+				{Title: "Target", Value: e.Target, Short: true},
+				{Title: "Probe", Value: e.ProbeType, Short: true},
+				{Title: "State", Value: e.State, Short: true},
+				{Title: "Previous", Value: e.Previous, Short: true},
+			},
+		}},
+	}
+
+	return postJSON(ctx, s.Client, s.WebhookURL, payload)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package analyze
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+// Defaults used when the corresponding Detector field is zero.
+const (
+	DefaultZThreshold = 3.0
+	DefaultAlpha      = 0.1
+	DefaultMinSamples = 20
+	DefaultMinStdDev  = time.Millisecond
+)
+
+// Detector keeps a rolling latency baseline per target and flags results
+// whose latency deviates from it by more than ZThreshold standard
+// deviations. The baseline is an exponentially weighted moving average and
+// variance, so it follows gradual drift while still catching sudden spikes.
+//
+// A Detector is safe for concurrent use.
+type Detector struct {
+	// ZThreshold is the z-score above which a result is flagged.
+	ZThreshold float64
+	// Alpha is the EWMA smoothing factor in (0, 1]; higher reacts faster.
+	Alpha float64
+	// MinSamples is the number of samples needed before anything is flagged.
+	MinSamples int
+	// MinStdDev floors the baseline deviation so that a very stable host
+	// is not flagged for sub-millisecond jitter.
+	MinStdDev time.Duration
+	// HourOfDay keeps a separate baseline for each hour of the day, for
+	// links whose latency follows a daily pattern.
+	HourOfDay bool
+
+	mu        sync.Mutex
+	baselines map[baselineKey]*baseline
+}
+
+type baselineKey struct {
+	target    string
+	probeType string
+	hour      int
+}
+
+type baseline struct {
+	n        int
+	mean     float64
+	variance float64
+}
+
+// Process implements monitor.Processor by calling Observe.
+func (d *Detector) Process(r *probe.Result) {
+	d.Observe(r)
+}
+
+// Observe scores r against its baseline, attaches r.Anomaly when the
+// deviation exceeds the threshold, then folds r into the baseline.
+// Failed results and results without latency are ignored.
+func (d *Detector) Observe(r *probe.Result) bool {
+	lat := Latency(*r)
+	if !r.Success || lat <= 0 {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.baselines == nil {
+		d.baselines = make(map[baselineKey]*baseline)
+	}
+
+	key := baselineKey{target: r.Target, probeType: r.ProbeType, hour: -1}
+	if d.HourOfDay {
+		key.hour = r.TimeStamp.Hour()
+	}
+
+	b, ok := d.baselines[key]
+	if !ok {
+		b = &baseline{}
+		d.baselines[key] = b
+	}
+
+	x := float64(lat)
+	flagged := false
+
+	if b.n >= d.minSamples() {
+		stddev := math.Max(math.Sqrt(b.variance), float64(d.minStdDev()))
+		z := (x - b.mean) / stddev
+
+		if z > d.zThreshold() {
+			flagged = true
+			r.Anomaly = &probe.AnomalyData{
+				ZScore:   z,
+				Latency:  lat,
+				Baseline: time.Duration(b.mean),
+				StdDev:   time.Duration(stddev),
+				Reason: fmt.Sprintf(
+					"latency %s is %.1f standard deviations above baseline (avg: %s, σ: %s)",
+					lat.Round(time.Microsecond),
+					z,
+					time.Duration(b.mean).Round(time.Microsecond),
+					time.Duration(stddev).Round(time.Microsecond),
+				),
+			}
+		}
+	}
+
+	b.update(x, d.alpha())
+	return flagged
+}
+
+// Seed warms the baselines up from historical results, which must be in
+// chronological order. Seeded results are not annotated.
+func (d *Detector) Seed(results []probe.Result) {
+	for _, r := range results {
+		d.Observe(&r)
+	}
+}
+
+// update folds x into the running mean and variance. Until 1/alpha samples
+// have been seen it uses a cumulative average so early samples are not
+// over-weighted.
+func (b *baseline) update(x, alpha float64) {
+	b.n++
+	if b.n == 1 {
+		b.mean = x
+		b.variance = 0
+		return
+	}
+
+	a := math.Max(alpha, 1/float64(b.n))
+	diff := x - b.mean
+	incr := a * diff
+	b.mean += incr
+	b.variance = (1 - a) * (b.variance + diff*incr)
+}
+
+func (d *Detector) zThreshold() float64 {
+	if d.ZThreshold > 0 {
+		return d.ZThreshold
+	}
+	return DefaultZThreshold
+}
+
+func (d *Detector) alpha() float64 {
+	if d.Alpha > 0 && d.Alpha <= 1 {
+		return d.Alpha
+	}
+	return DefaultAlpha
+}
+
+func (d *Detector) minSamples() int {
+	if d.MinSamples > 0 {
+		return d.MinSamples
+	}
+	return DefaultMinSamples
+}
+
+func (d *Detector) minStdDev() time.Duration {
+	if d.MinStdDev > 0 {
+		return d.MinStdDev
+	}
+	return DefaultMinStdDev
+}
@@ -0,0 +1,87 @@
+package analyze
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+func pingResult(ts time.Time, rtt time.Duration) probe.Result {
+	return probe.Result{
+		TimeStamp: ts,
+		Target:    "10.0.0.1",
+		ProbeType: "ping",
+		Success:   true,
+		Latency:   rtt,
+		PingData:  &probe.PingData{AvgRTT: rtt},
+	}
+}
+
+func TestDetectorFlagsSpike(t *testing.T) {
+	d := &Detector{}
+	now := time.Now()
+
+	// A stable ~2ms host with a little jitter.
+	for i := 0; i < 50; i++ {
+		r := pingResult(now, 2*time.Millisecond+time.Duration(i%5)*100*time.Microsecond)
+		if d.Observe(&r) {
+			t.Fatalf("sample %d flagged during warm-up: %+v", i, r.Anomaly)
+		}
+	}
+
+	// Well under the fixed 150ms ping threshold, but far outside the baseline.
+	spike := pingResult(now, 120*time.Millisecond)
+	if !d.Observe(&spike) {
+		t.Fatal("120ms spike on a 2ms host was not flagged")
+	}
+	if spike.Anomaly == nil || spike.Anomaly.ZScore <= DefaultZThreshold {
+		t.Errorf("Anomaly = %+v, want z-score above threshold", spike.Anomaly)
+	}
+	if !spike.IsAnomaly() {
+		t.Error("IsAnomaly() should report the flagged result")
+	}
+
+	normal := pingResult(now, 2200*time.Microsecond)
+	if d.Observe(&normal) {
+		t.Errorf("normal sample flagged after spike: %+v", normal.Anomaly)
+	}
+}
+
+func TestDetectorNeedsMinSamples(t *testing.T) {
+	d := &Detector{MinSamples: 10}
+	now := time.Now()
+
+	for i := 0; i < 5; i++ {
+		r := pingResult(now, 2*time.Millisecond)
+		d.Observe(&r)
+	}
+
+	spike := pingResult(now, time.Second)
+	if d.Observe(&spike) {
+		t.Error("flagged before MinSamples were collected")
+	}
+}
+
+func TestDetectorHourOfDay(t *testing.T) {
+	d := &Detector{HourOfDay: true, MinSamples: 5}
+	night := time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC)
+	day := time.Date(2026, 1, 1, 15, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 10; i++ {
+		n := pingResult(night, 5*time.Millisecond)
+		d.Observe(&n)
+		b := pingResult(day, 80*time.Millisecond)
+		d.Observe(&b)
+	}
+
+	busy := pingResult(day, 82*time.Millisecond)
+	if d.Observe(&busy) {
+		t.Error("daytime sample flagged against daytime baseline")
+	}
+
+	spike := pingResult(night, 80*time.Millisecond)
+	if !d.Observe(&spike) {
+		t.Error("daytime latency at night was not flagged")
+	}
+}
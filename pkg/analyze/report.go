@@ -0,0 +1,168 @@
+// Package analyze turns stored probe history into reports and flags
+// statistically unusual results.
+package analyze
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+// Report summarises the history of one target for one probe type.
+type Report struct {
+	Target    string `json:"target"`
+	ProbeType string `json:"probe_type"`
+
+	Probes    int     `json:"probes"`
+	Successes int     `json:"successes"`
+	UptimePct float64 `json:"uptime_pct"`
+
+	MinLatency time.Duration `json:"min_latency"`
+	AvgLatency time.Duration `json:"avg_latency"`
+	MaxLatency time.Duration `json:"max_latency"`
+	P50Latency time.Duration `json:"p50_latency"`
+	P95Latency time.Duration `json:"p95_latency"`
+	P99Latency time.Duration `json:"p99_latency"`
+
+	// Failures counts every non-OK result by severity name.
+	Failures map[string]int `json:"failures"`
+	// Anomalies counts results flagged by a Detector.
+	Anomalies int `json:"anomalies"`
+
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// BuildReports groups results by target and probe type and computes one
+// Report per group, ordered by target then probe type.
+func BuildReports(results []probe.Result) []Report {
+	type key struct{ target, probeType string }
+
+	groups := make(map[key][]probe.Result)
+	var order []key
+	for _, r := range results {
+		k := key{r.Target, r.ProbeType}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], r)
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].target != order[j].target {
+			return order[i].target < order[j].target
+		}
+		return order[i].probeType < order[j].probeType
+	})
+
+	reports := make([]Report, 0, len(order))
+	for _, k := range order {
+		reports = append(reports, buildReport(k.target, k.probeType, groups[k]))
+	}
+	return reports
+}
+
+func buildReport(target, probeType string, results []probe.Result) Report {
+	rep := Report{
+		Target:    target,
+		ProbeType: probeType,
+		Probes:    len(results),
+		Failures:  make(map[string]int),
+	}
+
+	var samples []time.Duration
+	for _, r := range results {
+		if rep.FirstSeen.IsZero() || r.TimeStamp.Before(rep.FirstSeen) {
+			rep.FirstSeen = r.TimeStamp
+		}
+		if r.TimeStamp.After(rep.LastSeen) {
+			rep.LastSeen = r.TimeStamp
+		}
+
+		if r.Success {
+			rep.Successes++
+			if lat := Latency(r); lat > 0 {
+				samples = append(samples, lat)
+			}
+		}
+		if !r.Success || r.Severity != probe.SeverityOK {
+			// Older rows can be failures stored with an OK severity.
+			severity := r.Severity
+			if !r.Success && severity == probe.SeverityOK {
+				severity = probe.SeverityError
+			}
+			rep.Failures[severity.String()]++
+		}
+		if r.Anomaly != nil {
+			rep.Anomalies++
+		}
+	}
+
+	if rep.Probes > 0 {
+		rep.UptimePct = float64(rep.Successes) / float64(rep.Probes) * 100
+	}
+
+	if len(samples) > 0 {
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+		var sum time.Duration
+		for _, s := range samples {
+			sum += s
+		}
+
+		rep.MinLatency = samples[0]
+		rep.MaxLatency = samples[len(samples)-1]
+		rep.AvgLatency = sum / time.Duration(len(samples))
+		rep.P50Latency = Percentile(samples, 50)
+		rep.P95Latency = Percentile(samples, 95)
+		rep.P99Latency = Percentile(samples, 99)
+	}
+
+	return rep
+}
+
+// Latency returns the latency sample used for statistics: the average ping
+// RTT when the result carries ping data, otherwise Result.Latency.
+func Latency(r probe.Result) time.Duration {
+	if r.PingData != nil && r.PingData.AvgRTT > 0 {
+		return r.PingData.AvgRTT
+	}
+	return r.Latency
+}
+
+// Percentile returns the nearest-rank p-th percentile of sorted, which must
+// be in ascending order.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// Worst returns the n worst reports: lowest uptime first, then highest P95
+// latency. A non-positive n returns every report in that order.
+func Worst(reports []Report, n int) []Report {
+	ranked := make([]Report, len(reports))
+	copy(ranked, reports)
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].UptimePct != ranked[j].UptimePct {
+			return ranked[i].UptimePct < ranked[j].UptimePct
+		}
+		return ranked[i].P95Latency > ranked[j].P95Latency
+	})
+
+	if n > 0 && n < len(ranked) {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
@@ -0,0 +1,80 @@
+package analyze
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+func TestPercentile(t *testing.T) {
+	var samples []time.Duration
+	for i := 1; i <= 100; i++ {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		name string
+		p    float64
+		want time.Duration
+	}{
+		{"P50", 50, 50 * time.Millisecond},
+		{"P95", 95, 95 * time.Millisecond},
+		{"P99", 99, 99 * time.Millisecond},
+		{"P100", 100, 100 * time.Millisecond},
+		{"P0", 0, 1 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Percentile(samples, tt.p); got != tt.want {
+				t.Errorf("Percentile(%v) = %v, want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("Percentile(nil) = %v, want 0", got)
+	}
+}
+
+func TestBuildReports(t *testing.T) {
+	now := time.Now()
+	results := []probe.Result{
+		{TimeStamp: now, Target: "10.0.0.1", ProbeType: "ping", Success: true,
+			Latency: time.Second, PingData: &probe.PingData{AvgRTT: 2 * time.Millisecond}},
+		{TimeStamp: now.Add(time.Minute), Target: "10.0.0.1", ProbeType: "ping", Success: true,
+			Severity: probe.SeverityWarning, PingData: &probe.PingData{AvgRTT: 4 * time.Millisecond}},
+		{TimeStamp: now.Add(2 * time.Minute), Target: "10.0.0.1", ProbeType: "ping", Success: false,
+			Severity: probe.SeverityError},
+		// A failure stored without a severity counts as an error.
+		{TimeStamp: now.Add(3 * time.Minute), Target: "10.0.0.1", ProbeType: "ping", Success: false,
+			Severity: probe.SeverityOK},
+		{TimeStamp: now, Target: "10.0.0.2", ProbeType: "http", Success: true, Latency: 30 * time.Millisecond},
+	}
+
+	reports := BuildReports(results)
+	if len(reports) != 2 {
+		t.Fatalf("BuildReports() returned %d reports, want 2", len(reports))
+	}
+
+	r := reports[0]
+	if r.Target != "10.0.0.1" || r.Probes != 4 || r.Successes != 2 {
+		t.Errorf("unexpected report: %+v", r)
+	}
+	if r.UptimePct != 50 {
+		t.Errorf("UptimePct = %v, want 50", r.UptimePct)
+	}
+	// Ping data takes precedence over the Result latency.
+	if r.MinLatency != 2*time.Millisecond || r.MaxLatency != 4*time.Millisecond {
+		t.Errorf("latency range = %v..%v, want 2ms..4ms", r.MinLatency, r.MaxLatency)
+	}
+	if r.Failures["Warning"] != 1 || r.Failures["Error"] != 2 || r.Failures["OK"] != 0 {
+		t.Errorf("Failures = %v, want 1 Warning and 2 Error", r.Failures)
+	}
+
+	worst := Worst(reports, 1)
+	if len(worst) != 1 || worst[0].Target != "10.0.0.1" {
+		t.Errorf("Worst() = %+v, want 10.0.0.1", worst)
+	}
+}
@@ -4,20 +4,34 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 type MonitorConfig struct {
-	Interval string `mapstructure:"interval"`
+	Interval string   `mapstructure:"interval"`
+	Targets  []string `mapstructure:"targets"`
 }
 
 type DatabaseConfig struct {
-	Path string `mapstructure:"path"`
+	Path          string `mapstructure:"path"`
+	RetentionDays int    `mapstructure:"retention_days"`
 }
 
 type MetricsConfig struct {
-	Enabled bool `mapstructure:"enabled"`
+	Enabled bool   `mapstructure:"enabled"`
+	Addr    string `mapstructure:"addr"`
+}
+
+type AlertConfig struct {
+	Webhooks         []string `mapstructure:"webhooks"`
+	SlackWebhook     string   `mapstructure:"slack_webhook"`
+	Exec             string   `mapstructure:"exec"`
+	Cooldown         string   `mapstructure:"cooldown"`
+	FailThreshold    int      `mapstructure:"fail_threshold"`
+	ResolveThreshold int      `mapstructure:"resolve_threshold"`
 }
 
 type ScanConfig struct {
@@ -29,6 +43,7 @@ type Config struct {
 	Monitor  MonitorConfig  `mapstructure:"monitor"`
 	Database DatabaseConfig `mapstructure:"database"`
 	Metrics  MetricsConfig  `mapstructure:"metrics"`
+	Alert    AlertConfig    `mapstructure:"alert"`
 	Scan     ScanConfig     `mapstructure:"scan"`
 }
 
@@ -48,7 +63,12 @@ func Load() error {
 	// Set defaults
 	viper.SetDefault("monitor.interval", "5m")
 	viper.SetDefault("database.path", "~/.netdiag.db")
+	viper.SetDefault("database.retention_days", 30)
 	viper.SetDefault("metrics.enabled", false)
+	viper.SetDefault("metrics.addr", ":9090")
+	viper.SetDefault("alert.cooldown", "5m")
+	viper.SetDefault("alert.fail_threshold", 3)
+	viper.SetDefault("alert.resolve_threshold", 2)
 	viper.SetDefault("scan.default_timeout", "1s")
 
 	viper.AutomaticEnv()
@@ -65,3 +85,16 @@ func Load() error {
 
 	return nil
 }
+
+// ExpandPath replaces a leading "~" in path with the user's home directory.
+func ExpandPath(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
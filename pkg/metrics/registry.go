@@ -0,0 +1,172 @@
+// Package metrics exports probe results as Prometheus metrics.
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+const namespace = "netdiag"
+
+// labels identifies every series by the probe that produced it.
+var labels = []string{"probe_type", "target"}
+
+// Registry holds the netdiag collectors and the Prometheus registry they are
+// registered with.
+type Registry struct {
+	reg *prometheus.Registry
+
+	ProbeTotal      *prometheus.CounterVec
+	ProbeFailures   *prometheus.CounterVec
+	Anomalies       *prometheus.CounterVec
+	ProbeSuccess    *prometheus.GaugeVec
+	ProbeSeverity   *prometheus.GaugeVec
+	ProbeLatency    *prometheus.GaugeVec
+	LastSuccess     *prometheus.GaugeVec
+	PingRTTMin      *prometheus.GaugeVec
+	PingRTTAvg      *prometheus.GaugeVec
+	PingRTTMax      *prometheus.GaugeVec
+	PingRTTStdDev   *prometheus.GaugeVec
+	PingPacketLoss  *prometheus.GaugeVec
+	HTTPStatusCode  *prometheus.GaugeVec
+	HTTPTLSDaysLeft *prometheus.GaugeVec
+	DNSRecords      *prometheus.GaugeVec
+	ScanOpenPorts   *prometheus.GaugeVec
+}
+
+// New creates a Registry with all netdiag metrics plus the standard Go and
+// process collectors registered.
+func New() *Registry {
+	r := &Registry{
+		reg: prometheus.NewRegistry(),
+
+		ProbeTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "probe_total",
+			Help:      "Number of probes run.",
+		}, labels),
+		ProbeFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "probe_failures_total",
+			Help:      "Number of probes that did not succeed.",
+		}, labels),
+		Anomalies: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "anomalies_total",
+			Help:      "Number of probes flagged as latency anomalies.",
+		}, labels),
+		ProbeSuccess: gauge("", "probe_success", "Whether the last probe succeeded (1) or not (0)."),
+		ProbeSeverity: gauge("", "probe_severity",
+			"Severity of the last probe (0=OK, 1=Warning, 2=Error, 3=Unknown)."),
+		ProbeLatency: gauge("", "probe_latency_seconds", "Latency reported by the last probe."),
+		LastSuccess: gauge("", "last_success_timestamp",
+			"Unix timestamp of the last successful probe."),
+
+		PingRTTMin:     gauge("ping", "rtt_min_seconds", "Minimum ICMP round-trip time of the last ping."),
+		PingRTTAvg:     gauge("ping", "rtt_avg_seconds", "Average ICMP round-trip time of the last ping."),
+		PingRTTMax:     gauge("ping", "rtt_max_seconds", "Maximum ICMP round-trip time of the last ping."),
+		PingRTTStdDev:  gauge("ping", "rtt_stddev_seconds", "Standard deviation of ICMP round-trip time of the last ping."),
+		PingPacketLoss: gauge("ping", "packet_loss_percent", "Percentage of ICMP packets lost in the last ping."),
+
+		HTTPStatusCode:  gauge("http", "status_code", "HTTP status code of the last request."),
+		HTTPTLSDaysLeft: gauge("http", "tls_days_left", "Days until the TLS certificate expires."),
+
+		DNSRecords: gauge("dns", "records", "Number of records returned by the last DNS lookup."),
+
+		ScanOpenPorts: gauge("scan", "open_ports", "Number of open ports found by the last scan."),
+	}
+
+	r.reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		r.ProbeTotal,
+		r.ProbeFailures,
+		r.Anomalies,
+		r.ProbeSuccess,
+		r.ProbeSeverity,
+		r.ProbeLatency,
+		r.LastSuccess,
+		r.PingRTTMin,
+		r.PingRTTAvg,
+		r.PingRTTMax,
+		r.PingRTTStdDev,
+		r.PingPacketLoss,
+		r.HTTPStatusCode,
+		r.HTTPTLSDaysLeft,
+		r.DNSRecords,
+		r.ScanOpenPorts,
+	)
+
+	return r
+}
+
+func gauge(subsystem, name, help string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      help,
+	}, labels)
+}
+
+// Observe updates every metric that applies to r.
+func (r *Registry) Observe(res probe.Result) {
+	l := prometheus.Labels{"probe_type": res.ProbeType, "target": res.Target}
+
+	r.ProbeTotal.With(l).Inc()
+	r.ProbeSeverity.With(l).Set(float64(res.Severity))
+	r.ProbeLatency.With(l).Set(res.Latency.Seconds())
+
+	if res.Success {
+		r.ProbeSuccess.With(l).Set(1)
+		r.LastSuccess.With(l).Set(float64(res.TimeStamp.Unix()))
+	} else {
+		r.ProbeSuccess.With(l).Set(0)
+		r.ProbeFailures.With(l).Inc()
+	}
+
+	if res.Anomaly != nil {
+		r.Anomalies.With(l).Inc()
+	}
+
+	if d := res.PingData; d != nil {
+		r.PingRTTMin.With(l).Set(d.MinRTT.Seconds())
+		r.PingRTTAvg.With(l).Set(d.AvgRTT.Seconds())
+		r.PingRTTMax.With(l).Set(d.MaxRTT.Seconds())
+		r.PingRTTStdDev.With(l).Set(d.StdDevRTT.Seconds())
+		r.PingPacketLoss.With(l).Set(d.PacketLoss)
+	}
+
+	if d := res.HTTPData; d != nil {
+		r.HTTPStatusCode.With(l).Set(float64(d.StatusCode))
+		if d.TLSValid || d.TLSDaysLeft != 0 {
+			r.HTTPTLSDaysLeft.With(l).Set(float64(d.TLSDaysLeft))
+		}
+	}
+
+	if d := res.DNSData; d != nil {
+		r.DNSRecords.With(l).Set(float64(len(d.Records)))
+	}
+
+	if d := res.ScanData; d != nil {
+		r.ScanOpenPorts.With(l).Set(float64(len(d.OpenPorts)))
+	}
+}
+
+// Write implements monitor.Sink.
+func (r *Registry) Write(_ context.Context, res probe.Result) error {
+	r.Observe(res)
+	return nil
+}
+
+// Handler returns an http.Handler serving the registry in the Prometheus
+// text exposition format.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{Registry: r.reg})
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+func TestScrapeMetrics(t *testing.T) {
+	reg := New()
+
+	now := time.Unix(1700000000, 0)
+	reg.Observe(probe.Result{
+		TimeStamp: now,
+		ProbeType: "ping",
+		Target:    "10.0.0.1",
+		Success:   true,
+		Latency:   12 * time.Millisecond,
+		PingData: &probe.PingData{
+			MinRTT:     10 * time.Millisecond,
+			AvgRTT:     12 * time.Millisecond,
+			MaxRTT:     15 * time.Millisecond,
+			StdDevRTT:  2 * time.Millisecond,
+			PacketLoss: 25,
+		},
+	})
+	reg.Observe(probe.Result{
+		TimeStamp: now,
+		ProbeType: "http",
+		Target:    "https://example.com",
+		Success:   false,
+		Severity:  probe.SeverityError,
+		HTTPData:  &probe.HTTPData{StatusCode: 503, TLSValid: true, TLSDaysLeft: 42},
+	})
+	reg.Observe(probe.Result{
+		TimeStamp: now,
+		ProbeType: "scan",
+		Target:    "db01",
+		Success:   true,
+		ScanData:  &probe.ScanData{OpenPorts: []int{22, 5432}},
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, ln, reg) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	}()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatalf("scrape: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	text := string(body)
+
+	want := []string{
+		`netdiag_ping_rtt_avg_seconds{probe_type="ping",target="10.0.0.1"} 0.012`,
+		`netdiag_ping_packet_loss_percent{probe_type="ping",target="10.0.0.1"} 25`,
+		`netdiag_last_success_timestamp{probe_type="ping",target="10.0.0.1"} 1.7e+09`,
+		`netdiag_http_status_code{probe_type="http",target="https://example.com"} 503`,
+		`netdiag_http_tls_days_left{probe_type="http",target="https://example.com"} 42`,
+		`netdiag_probe_failures_total{probe_type="http",target="https://example.com"} 1`,
+		`netdiag_scan_open_ports{probe_type="scan",target="db01"} 2`,
+	}
+	for _, w := range want {
+		if !strings.Contains(text, w) {
+			t.Errorf("scrape output missing %q", w)
+		}
+	}
+
+	if strings.Contains(text, `netdiag_last_success_timestamp{probe_type="http"`) {
+		t.Error("failed probe must not set last_success_timestamp")
+	}
+}
+
+func TestServeFailureReleasesShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	_ = ln.Close()
+
+	if err := Serve(context.Background(), ln, New()); err == nil {
+		t.Fatal("Serve() on a closed listener returned nil")
+	}
+
+	// The shutdown goroutine must exit even though ctx is never cancelled.
+	buf := make([]byte, 1<<20)
+	deadline := time.Now().Add(time.Second)
+	for {
+		stacks := string(buf[:runtime.Stack(buf, true)])
+		if !strings.Contains(stacks, "metrics.Serve.func") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("shutdown goroutine still running after Serve failed:\n%s", stacks)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,176 @@
+// Package monitor runs probers on a schedule and fans their results out to sinks.
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+// Target is a single prober scheduled by the monitor.
+type Target struct {
+	// Name identifies the target in results produced when the prober
+	// returns a hard error instead of a Result.
+	Name   string
+	Prober probe.Prober
+	// Interval overrides Monitor.Interval for this target when non-zero.
+	Interval time.Duration
+}
+
+// Processor inspects or annotates a result before it reaches the sinks,
+// e.g. to attach anomaly information.
+type Processor interface {
+	Process(r *probe.Result)
+}
+
+// Monitor schedules every target on its own interval until the context is
+// cancelled. Results pass through the processors and then the sinks, in
+// order, from a single goroutine, so neither needs to be safe for
+// concurrent use.
+type Monitor struct {
+	Targets    []Target
+	Processors []Processor
+	Sinks      []Sink
+	Interval   time.Duration
+	// Jitter spreads probes by up to this fraction of the interval
+	// (0.1 = ±10%) so targets sharing an interval do not fire in lockstep.
+	Jitter float64
+	Logger *slog.Logger
+}
+
+// Run blocks until ctx is cancelled. It returns nil on a clean shutdown.
+func (m *Monitor) Run(ctx context.Context) error {
+	if len(m.Targets) == 0 {
+		return errors.New("no monitor targets configured")
+	}
+	if m.Logger == nil {
+		m.Logger = slog.Default()
+	}
+
+	for _, t := range m.Targets {
+		if m.intervalFor(t) <= 0 {
+			return fmt.Errorf("target %q has no interval", t.Name)
+		}
+	}
+
+	results := make(chan probe.Result)
+	dispatched := make(chan struct{})
+
+	// Results already in flight when ctx is cancelled are still flushed.
+	sinkCtx := context.WithoutCancel(ctx)
+	go func() {
+		defer close(dispatched)
+		for r := range results {
+			m.dispatch(sinkCtx, r)
+		}
+	}()
+
+	grp, gctx := errgroup.WithContext(ctx)
+	for _, t := range m.Targets {
+		grp.Go(func() error {
+			m.schedule(gctx, t, results)
+			return nil
+		})
+	}
+
+	err := grp.Wait()
+	close(results)
+	<-dispatched
+
+	m.Logger.Info("monitor shutting down gracefully")
+	return err
+}
+
+// schedule runs a single target until ctx is cancelled. The first probe fires
+// immediately (or after a random stagger when jitter is enabled).
+func (m *Monitor) schedule(ctx context.Context, t Target, out chan<- probe.Result) {
+	interval := m.intervalFor(t)
+
+	var first time.Duration
+	if m.Jitter > 0 {
+		first = time.Duration(rand.Float64() * m.Jitter * float64(interval))
+	}
+
+	timer := time.NewTimer(first)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		result := m.runOnce(ctx, t)
+
+		// A probe cut short by shutdown is not a meaningful sample.
+		if ctx.Err() != nil {
+			return
+		}
+
+		select {
+		case out <- result:
+		case <-ctx.Done():
+			return
+		}
+
+		timer.Reset(m.next(interval))
+	}
+}
+
+func (m *Monitor) runOnce(ctx context.Context, t Target) probe.Result {
+	result, err := t.Prober.Probe(ctx)
+	if err != nil {
+		m.Logger.Warn("probe failed", "type", t.Prober.Type(), "target", t.Name, "error", err)
+		result = probe.Result{
+			Target:    t.Name,
+			ProbeType: t.Prober.Type(),
+			Success:   false,
+			Severity:  probe.SeverityError,
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+		}
+	}
+	if result.Target == "" {
+		result.Target = t.Name
+	}
+	return result
+}
+
+func (m *Monitor) dispatch(ctx context.Context, r probe.Result) {
+	for _, p := range m.Processors {
+		p.Process(&r)
+	}
+	for _, s := range m.Sinks {
+		if err := s.Write(ctx, r); err != nil {
+			m.Logger.Error("sink write failed", "target", r.Target, "error", err)
+		}
+	}
+}
+
+func (m *Monitor) intervalFor(t Target) time.Duration {
+	if t.Interval > 0 {
+		return t.Interval
+	}
+	return m.Interval
+}
+
+// next returns interval adjusted by a random offset within ±Jitter.
+func (m *Monitor) next(interval time.Duration) time.Duration {
+	if m.Jitter <= 0 {
+		return interval
+	}
+	offset := (rand.Float64()*2 - 1) * m.Jitter * float64(interval)
+	d := interval + time.Duration(offset)
+	if d <= 0 {
+		return interval
+	}
+	return d
+}
@@ -0,0 +1,134 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+type fakeProber struct {
+	target string
+	err    error
+}
+
+func (f *fakeProber) Type() string { return "fake" }
+
+func (f *fakeProber) Probe(_ context.Context) (probe.Result, error) {
+	if f.err != nil {
+		return probe.Result{}, f.err
+	}
+	return probe.Result{
+		Target:    f.target,
+		ProbeType: "fake",
+		Success:   true,
+		Severity:  probe.SeverityOK,
+		TimeStamp: time.Now(),
+	}, nil
+}
+
+type recordingSink struct {
+	mu      sync.Mutex
+	results []probe.Result
+}
+
+func (r *recordingSink) Write(_ context.Context, res probe.Result) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, res)
+	return nil
+}
+
+func (r *recordingSink) count(target string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, res := range r.results {
+		if res.Target == target {
+			n++
+		}
+	}
+	return n
+}
+
+func TestMonitorRun(t *testing.T) {
+	sink := &recordingSink{}
+	mon := &Monitor{
+		Targets: []Target{
+			{Name: "fast", Prober: &fakeProber{target: "fast"}, Interval: 10 * time.Millisecond},
+			{Name: "slow", Prober: &fakeProber{target: "slow"}},
+			{Name: "broken", Prober: &fakeProber{err: errors.New("boom")}},
+		},
+		Sinks:    []Sink{sink},
+		Interval: time.Hour,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := mon.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if got := sink.count("fast"); got < 3 {
+		t.Errorf("fast target probed %d times, want at least 3", got)
+	}
+	// The first probe fires immediately; the next one is an hour away.
+	if got := sink.count("slow"); got != 1 {
+		t.Errorf("slow target probed %d times, want 1", got)
+	}
+	if got := sink.count("broken"); got != 1 {
+		t.Errorf("broken target produced %d results, want 1", got)
+	}
+
+	for _, r := range sink.results {
+		if r.Target == "broken" && (r.Success || r.Severity != probe.SeverityError) {
+			t.Errorf("probe error not reported as failure: %+v", r)
+		}
+	}
+}
+
+type tagProcessor struct{}
+
+func (tagProcessor) Process(r *probe.Result) { r.Message = "processed" }
+
+func TestMonitorProcessors(t *testing.T) {
+	sink := &recordingSink{}
+	mon := &Monitor{
+		Targets:    []Target{{Name: "x", Prober: &fakeProber{target: "x"}}},
+		Processors: []Processor{tagProcessor{}},
+		Sinks:      []Sink{sink},
+		Interval:   time.Hour,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	_ = mon.Run(ctx)
+
+	if len(sink.results) != 1 || sink.results[0].Message != "processed" {
+		t.Errorf("processor not applied before sinks: %+v", sink.results)
+	}
+}
+
+func TestMonitorRequiresInterval(t *testing.T) {
+	mon := &Monitor{
+		Targets: []Target{{Name: "x", Prober: &fakeProber{target: "x"}}},
+	}
+	if err := mon.Run(context.Background()); err == nil {
+		t.Error("Run() with no interval should fail")
+	}
+}
+
+func TestNextJitter(t *testing.T) {
+	m := &Monitor{Jitter: 0.2}
+	interval := time.Second
+	for i := 0; i < 100; i++ {
+		d := m.next(interval)
+		if d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("next() = %v, want within ±20%% of %v", d, interval)
+		}
+	}
+}
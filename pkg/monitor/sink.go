@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+// Sink receives every result produced by the monitor.
+type Sink interface {
+	Write(ctx context.Context, r probe.Result) error
+}
+
+// SinkFunc adapts an ordinary function to the Sink interface.
+type SinkFunc func(ctx context.Context, r probe.Result) error
+
+// Write calls f(ctx, r).
+func (f SinkFunc) Write(ctx context.Context, r probe.Result) error {
+	return f(ctx, r)
+}
+
+// ConsoleSink prints one line per result, or one JSON object per line when
+// JSON is set.
+type ConsoleSink struct {
+	W    io.Writer
+	JSON bool
+}
+
+func (c *ConsoleSink) Write(_ context.Context, r probe.Result) error {
+	if c.JSON {
+		return json.NewEncoder(c.W).Encode(r)
+	}
+
+	message := r.Message
+	if r.Anomaly != nil {
+		message += fmt.Sprintf(" [anomaly: z=%.1f, baseline %s]",
+			r.Anomaly.ZScore, r.Anomaly.Baseline.Round(time.Microsecond))
+	}
+
+	_, err := fmt.Fprintf(c.W, "%s  %-7s  %-8s  %-30s  %10s  %s\n",
+		r.TimeStamp.Format(time.TimeOnly),
+		r.Severity.String(),
+		r.ProbeType,
+		r.Target,
+		r.Latency.Round(time.Microsecond).String(),
+		message,
+	)
+	return err
+}
+
+// JSONLSink appends each result as a JSON object on its own line.
+type JSONLSink struct {
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewJSONLSink opens (or creates) path for appending.
+func NewJSONLSink(path string) (*JSONLSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLSink{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (j *JSONLSink) Write(_ context.Context, r probe.Result) error {
+	return j.enc.Encode(r)
+}
+
+// Close closes the underlying file.
+func (j *JSONLSink) Close() error {
+	return j.file.Close()
+}
@@ -0,0 +1,513 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+// NmapRun describes a finished scan for WriteNmapXML and WriteGrepable.
+type NmapRun struct {
+	// Args is the command line, recorded in the output header.
+	Args    string
+	Version string
+	Start   time.Time
+	// Ports is the requested port set, reported as the scanned services.
+	Ports   probe.PortSet
+	Results []probe.Result
+	// Down lists hosts that were skipped because they did not respond.
+	Down []string
+}
+
+// extraPortsThreshold is the number of ports in one state above which they
+// are summarised instead of listed, as nmap does.
+const extraPortsThreshold = 25
+
+// nmap's XML schema (nmap.dtd), limited to what a port scan produces.
+type (
+	xmlRun struct {
+		XMLName          xml.Name      `xml:"nmaprun"`
+		Scanner          string        `xml:"scanner,attr"`
+		Args             string        `xml:"args,attr"`
+		Start            int64         `xml:"start,attr"`
+		StartStr         string        `xml:"startstr,attr"`
+		Version          string        `xml:"version,attr"`
+		XMLOutputVersion string        `xml:"xmloutputversion,attr"`
+		ScanInfo         []xmlScanInfo `xml:"scaninfo"`
+		Verbose          xmlLevel      `xml:"verbose"`
+		Debugging        xmlLevel      `xml:"debugging"`
+		Hosts            []xmlHost     `xml:"host"`
+		RunStats         xmlRunStats   `xml:"runstats"`
+	}
+	xmlScanInfo struct {
+		Type        string `xml:"type,attr"`
+		Protocol    string `xml:"protocol,attr"`
+		NumServices int    `xml:"numservices,attr"`
+		Services    string `xml:"services,attr"`
+	}
+	xmlLevel struct {
+		Level int `xml:"level,attr"`
+	}
+	xmlHost struct {
+		StartTime int64         `xml:"starttime,attr,omitempty"`
+		EndTime   int64         `xml:"endtime,attr,omitempty"`
+		Status    xmlStatus     `xml:"status"`
+		Address   xmlAddress    `xml:"address"`
+		Hostnames *xmlHostnames `xml:"hostnames,omitempty"`
+		Ports     *xmlPorts     `xml:"ports,omitempty"`
+		Times     *xmlTimes     `xml:"times,omitempty"`
+	}
+	xmlStatus struct {
+		State  string `xml:"state,attr"`
+		Reason string `xml:"reason,attr"`
+	}
+	xmlAddress struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+	}
+	xmlHostnames struct {
+		Hostnames []xmlHostname `xml:"hostname"`
+	}
+	xmlHostname struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+	}
+	xmlPorts struct {
+		Extra []xmlExtraPorts `xml:"extraports"`
+		Ports []xmlPort       `xml:"port"`
+	}
+	xmlExtraPorts struct {
+		State   string           `xml:"state,attr"`
+		Count   int              `xml:"count,attr"`
+		Reasons []xmlExtraReason `xml:"extrareasons"`
+	}
+	xmlExtraReason struct {
+		Reason string `xml:"reason,attr"`
+		Count  int    `xml:"count,attr"`
+	}
+	xmlPort struct {
+		Protocol string      `xml:"protocol,attr"`
+		PortID   int         `xml:"portid,attr"`
+		State    xmlState    `xml:"state"`
+		Service  *xmlService `xml:"service,omitempty"`
+	}
+	xmlState struct {
+		State     string `xml:"state,attr"`
+		Reason    string `xml:"reason,attr"`
+		ReasonTTL int    `xml:"reason_ttl,attr"`
+	}
+	xmlService struct {
+		Name    string `xml:"name,attr"`
+		Product string `xml:"product,attr,omitempty"`
+		Version string `xml:"version,attr,omitempty"`
+		Tunnel  string `xml:"tunnel,attr,omitempty"`
+		Method  string `xml:"method,attr"`
+		Conf    int    `xml:"conf,attr"`
+	}
+	xmlTimes struct {
+		SRTT   int64 `xml:"srtt,attr"`
+		RTTVar int64 `xml:"rttvar,attr"`
+		To     int64 `xml:"to,attr"`
+	}
+	xmlRunStats struct {
+		Finished xmlFinished `xml:"finished"`
+		Hosts    xmlHostStat `xml:"hosts"`
+	}
+	xmlFinished struct {
+		Time    int64  `xml:"time,attr"`
+		TimeStr string `xml:"timestr,attr"`
+		Elapsed string `xml:"elapsed,attr"`
+		Summary string `xml:"summary,attr"`
+		Exit    string `xml:"exit,attr"`
+	}
+	xmlHostStat struct {
+		Up    int `xml:"up,attr"`
+		Down  int `xml:"down,attr"`
+		Total int `xml:"total,attr"`
+	}
+)
+
+// scannedPort is one port with its state and any detected service.
+type scannedPort struct {
+	port    int
+	state   probe.PortState
+	service *probe.ServiceInfo
+}
+
+// WriteNmapXML writes run in nmap's XML output format (-oX), so netdiag
+// scans can feed tools that ingest nmap results.
+func WriteNmapXML(w io.Writer, run NmapRun) error {
+	end := run.end()
+	up := 0
+
+	doc := xmlRun{
+		Scanner:          "netdiag",
+		Args:             run.Args,
+		Start:            run.Start.Unix(),
+		StartStr:         run.Start.Format(time.ANSIC),
+		Version:          run.Version,
+		XMLOutputVersion: "1.05",
+	}
+
+	for _, info := range run.scanInfo() {
+		doc.ScanInfo = append(doc.ScanInfo, xmlScanInfo{
+			Type:        info.method,
+			Protocol:    info.protocol,
+			NumServices: run.Ports.Len(),
+			Services:    run.Ports.String(),
+		})
+	}
+
+	for _, r := range run.Results {
+		host := downHost(r.Target, resolvedAddress(r))
+		if data := r.ScanData; r.Success && data != nil {
+			up++
+			host.Status = xmlStatus{State: "up", Reason: "user-set"}
+			host.StartTime = r.TimeStamp.Add(-r.Latency).Unix()
+			host.EndTime = r.TimeStamp.Unix()
+			host.Ports = xmlPortsFor(data)
+			if data.RTT > 0 {
+				host.Times = &xmlTimes{
+					SRTT:   data.RTT.Microseconds(),
+					RTTVar: max(data.AdaptiveTimeout-data.RTT, 0).Microseconds() / 4,
+					To:     data.AdaptiveTimeout.Microseconds(),
+				}
+			}
+		}
+		doc.Hosts = append(doc.Hosts, host)
+	}
+
+	for _, target := range run.Down {
+		doc.Hosts = append(doc.Hosts, downHost(target, ""))
+	}
+
+	total := len(run.Results) + len(run.Down)
+	elapsed := end.Sub(run.Start).Seconds()
+	doc.RunStats = xmlRunStats{
+		Finished: xmlFinished{
+			Time:    end.Unix(),
+			TimeStr: end.Format(time.ANSIC),
+			Elapsed: strconv.FormatFloat(elapsed, 'f', 2, 64),
+			Summary: fmt.Sprintf("netdiag done at %s; %s scanned in %.2f seconds",
+				end.Format(time.ANSIC), hostCount(total, up), elapsed),
+			Exit: "success",
+		},
+		Hosts: xmlHostStat{Up: up, Down: total - up, Total: total},
+	}
+
+	if _, err := io.WriteString(w, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// downHost returns the <host> element for target with status down. resolved
+// is the address target was resolved to, if any.
+func downHost(target, resolved string) xmlHost {
+	addr, addrType, hostname := hostAddress(target, resolved)
+	host := xmlHost{
+		Status:  xmlStatus{State: "down", Reason: "no-response"},
+		Address: xmlAddress{Addr: addr, AddrType: addrType},
+	}
+	if hostname != "" {
+		host.Hostnames = &xmlHostnames{Hostnames: []xmlHostname{{Name: hostname, Type: "user"}}}
+	}
+	return host
+}
+
+// xmlPortsFor lists open ports individually and summarises any other state
+// with more than extraPortsThreshold ports as <extraports>.
+func xmlPortsFor(data *probe.ScanData) *xmlPorts {
+	ports := &xmlPorts{}
+	collapsed := collapsedStates(data, false)
+
+	for _, state := range portStates {
+		if !collapsed[state] {
+			continue
+		}
+		count := len(data.Ports(state))
+		ports.Extra = append(ports.Extra, xmlExtraPorts{
+			State:   nmapState(state),
+			Count:   count,
+			Reasons: []xmlExtraReason{{Reason: nmapReason(state, data.ScanMethod), Count: count}},
+		})
+	}
+
+	for _, p := range listedPorts(data, collapsed) {
+		port := xmlPort{
+			Protocol: protocolOf(data),
+			PortID:   p.port,
+			State:    xmlState{State: nmapState(p.state), Reason: nmapReason(p.state, data.ScanMethod)},
+		}
+		if p.service != nil {
+			product, version := splitProductVersion(p.service.Version)
+			port.Service = &xmlService{
+				Name:    p.service.Service,
+				Product: product,
+				Version: version,
+				Method:  "probed",
+				Conf:    10,
+			}
+			if p.service.TLS {
+				port.Service.Tunnel = "ssl"
+			}
+		} else if name := probe.ServiceName(p.port, protocolOf(data)); name != "" {
+			port.Service = &xmlService{Name: name, Method: "table", Conf: 3}
+		}
+		ports.Ports = append(ports.Ports, port)
+	}
+	return ports
+}
+
+// WriteGrepable writes run in nmap's grepable output format (-oG): one
+// "Host:" line per host with its status, and one with its ports.
+func WriteGrepable(w io.Writer, run NmapRun) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# netdiag %s scan initiated %s as: %s\n", run.Version, run.Start.Format(time.ANSIC), run.Args)
+
+	up := 0
+	for _, r := range run.Results {
+		addr, _, hostname := hostAddress(r.Target, resolvedAddress(r))
+		prefix := fmt.Sprintf("Host: %s (%s)", addr, hostname)
+
+		data := r.ScanData
+		if !r.Success || data == nil {
+			fmt.Fprintf(&b, "%s\tStatus: Down\n", prefix)
+			continue
+		}
+		up++
+		fmt.Fprintf(&b, "%s\tStatus: Up\n", prefix)
+
+		collapsed := collapsedStates(data, true)
+		var fields []string
+		for _, p := range listedPorts(data, collapsed) {
+			service, version := probe.ServiceName(p.port, protocolOf(data)), ""
+			if p.service != nil {
+				service, version = p.service.Service, p.service.Version
+				if p.service.TLS {
+					service = "ssl|" + service
+				}
+			}
+			fields = append(fields, fmt.Sprintf("%d/%s/%s//%s//%s/",
+				p.port, nmapState(p.state), protocolOf(data), grepableEscape(service), grepableEscape(version)))
+		}
+
+		// Like nmap, leave out the Ports field when every port was collapsed,
+		// and the whole line when there is nothing to report.
+		line := prefix
+		if len(fields) > 0 {
+			line += "\tPorts: " + strings.Join(fields, ", ")
+		}
+		for state := range collapsed {
+			line += fmt.Sprintf("\tIgnored State: %s (%d)", nmapState(state), len(data.Ports(state)))
+		}
+		if line != prefix {
+			b.WriteString(line + "\n")
+		}
+	}
+
+	for _, target := range run.Down {
+		addr, _, hostname := hostAddress(target, "")
+		fmt.Fprintf(&b, "Host: %s (%s)\tStatus: Down\n", addr, hostname)
+	}
+
+	end := run.end()
+	fmt.Fprintf(&b, "# netdiag done at %s -- %s scanned in %.2f seconds\n",
+		end.Format(time.ANSIC), hostCount(len(run.Results)+len(run.Down), up), end.Sub(run.Start).Seconds())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// portStates is the order in which states are summarised.
+var portStates = []probe.PortState{
+	probe.PortClosed, probe.PortFiltered, probe.PortUnreachable, probe.PortOpenFiltered, probe.PortOpen,
+}
+
+// collapsedStates picks the non-open states too large to list port by port.
+// Grepable output has room for a single "Ignored State", so with single set
+// only the largest qualifying state is returned.
+func collapsedStates(data *probe.ScanData, single bool) map[probe.PortState]bool {
+	collapsed := make(map[probe.PortState]bool)
+	var largest probe.PortState
+	for _, state := range portStates {
+		if state == probe.PortOpen || len(data.Ports(state)) <= extraPortsThreshold {
+			continue
+		}
+		collapsed[state] = true
+		if largest == "" || len(data.Ports(state)) > len(data.Ports(largest)) {
+			largest = state
+		}
+	}
+	if single && largest != "" {
+		return map[probe.PortState]bool{largest: true}
+	}
+	return collapsed
+}
+
+// listedPorts returns the ports in states that are not collapsed, in port
+// order, paired with any detected service.
+func listedPorts(data *probe.ScanData, collapsed map[probe.PortState]bool) []scannedPort {
+	services := make(map[int]*probe.ServiceInfo, len(data.Services))
+	for i := range data.Services {
+		services[data.Services[i].Port] = &data.Services[i]
+	}
+
+	var ports []scannedPort
+	for _, state := range portStates {
+		if collapsed[state] {
+			continue
+		}
+		for _, p := range data.Ports(state) {
+			ports = append(ports, scannedPort{port: p, state: state, service: services[p]})
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].port < ports[j].port })
+	return ports
+}
+
+// nmapState maps a PortState to nmap's vocabulary. nmap has no separate
+// unreachable state; it reports those ports as filtered.
+func nmapState(state probe.PortState) string {
+	if state == probe.PortUnreachable {
+		return "filtered"
+	}
+	return string(state)
+}
+
+// nmapReason gives the reason nmap would record for state under method.
+func nmapReason(state probe.PortState, method string) string {
+	switch state {
+	case probe.PortOpen:
+		if method == "udp" {
+			return "udp-response"
+		}
+		return "syn-ack"
+	case probe.PortClosed:
+		switch method {
+		case "udp":
+			return "port-unreach"
+		case "syn":
+			return "reset"
+		default:
+			return "conn-refused"
+		}
+	case probe.PortUnreachable:
+		return "host-unreach"
+	default:
+		return "no-response"
+	}
+}
+
+// protocolOf returns the scanned protocol, defaulting to TCP for results
+// recorded before ScanData carried one.
+func protocolOf(data *probe.ScanData) string {
+	if data.Protocol == "" {
+		return "tcp"
+	}
+	return data.Protocol
+}
+
+type scanInfo struct {
+	method   string
+	protocol string
+}
+
+// scanInfo returns each distinct method/protocol pair among the results.
+func (run NmapRun) scanInfo() []scanInfo {
+	var infos []scanInfo
+	seen := make(map[scanInfo]bool)
+	for _, r := range run.Results {
+		if r.ScanData == nil {
+			continue
+		}
+		info := scanInfo{method: r.ScanData.ScanMethod, protocol: protocolOf(r.ScanData)}
+		if !seen[info] {
+			seen[info] = true
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// end returns when the last host finished, or Start if none did.
+func (run NmapRun) end() time.Time {
+	end := run.Start
+	for _, r := range run.Results {
+		if r.TimeStamp.After(end) {
+			end = r.TimeStamp
+		}
+	}
+	return end
+}
+
+// hostAddress returns the address to report for target, its type and the
+// user-supplied hostname, if target was not already an address. resolved is
+// the address the scan resolved target to (ScanData.Address); without one, a
+// hostname is reported as given.
+func hostAddress(target, resolved string) (addr, addrType, hostname string) {
+	ip := net.ParseIP(target)
+	if ip == nil {
+		hostname = target
+		ip = net.ParseIP(resolved)
+	}
+	switch {
+	case ip == nil:
+		return target, "ipv4", hostname
+	case ip.To4() != nil:
+		return ip.String(), "ipv4", hostname
+	default:
+		return ip.String(), "ipv6", hostname
+	}
+}
+
+// resolvedAddress returns the address r's scan resolved its target to.
+func resolvedAddress(r probe.Result) string {
+	if r.ScanData == nil {
+		return ""
+	}
+	return r.ScanData.Address
+}
+
+// hostCount phrases a host total the way nmap's summaries do.
+func hostCount(total, up int) string {
+	noun := "addresses"
+	if total == 1 {
+		noun = "address"
+	}
+	verb := "hosts"
+	if up == 1 {
+		verb = "host"
+	}
+	return fmt.Sprintf("%d IP %s (%d %s up)", total, noun, up, verb)
+}
+
+// splitProductVersion splits a detected version string such as
+// "nginx/1.18.0", "OpenSSH_8.9p1" or "vsFTPd 3.0.3" into product and
+// version. Strings without a recognisable version are all product.
+func splitProductVersion(s string) (product, version string) {
+	for i := 1; i < len(s)-1; i++ {
+		if strings.ContainsRune("/_ ", rune(s[i])) && s[i+1] >= '0' && s[i+1] <= '9' {
+			return s[:i], s[i+1:]
+		}
+	}
+	return s, ""
+}
+
+// grepableEscape replaces the characters that delimit grepable port fields.
+func grepableEscape(s string) string {
+	return strings.NewReplacer("/", "|", ",", " ").Replace(s)
+}
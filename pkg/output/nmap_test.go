@@ -0,0 +1,184 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+func testRun() NmapRun {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	closed := make([]int, 0, 30)
+	for p := 1000; p < 1030; p++ {
+		closed = append(closed, p)
+	}
+
+	return NmapRun{
+		Args:    "netdiag scan 10.0.0.5 -p 22,80,443,1000-1029",
+		Version: "1.0.0",
+		Start:   start,
+		Ports:   probe.PortSet{{Start: 22, End: 22}, {Start: 80, End: 80}, {Start: 443, End: 443}, {Start: 1000, End: 1029}},
+		Results: []probe.Result{{
+			Target:    "10.0.0.5",
+			ProbeType: "scan",
+			Success:   true,
+			TimeStamp: start.Add(2 * time.Second),
+			Latency:   2 * time.Second,
+			ScanData: &probe.ScanData{
+				TotalPorts:    33,
+				OpenPorts:     []int{22, 443},
+				ClosedPorts:   closed,
+				FilteredPorts: []int{80},
+				Protocol:      "tcp",
+				ScanMethod:    "connect",
+				Services: []probe.ServiceInfo{
+					{Port: 22, Service: "ssh", Version: "OpenSSH_8.9p1"},
+					{Port: 443, Service: "http", Version: "nginx/1.18.0", TLS: true},
+				},
+			},
+		}},
+		Down: []string{"10.0.0.6"},
+	}
+}
+
+func TestWriteNmapXML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteNmapXML(&buf, testRun()); err != nil {
+		t.Fatalf("WriteNmapXML() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), `<?xml version="1.0" encoding="UTF-8"?>`) {
+		t.Errorf("missing XML declaration:\n%s", buf.String())
+	}
+
+	var doc xmlRun
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output does not parse: %v", err)
+	}
+
+	if doc.Scanner != "netdiag" || len(doc.ScanInfo) != 1 || doc.ScanInfo[0].Services != "22,80,443,1000-1029" {
+		t.Errorf("header = %+v", doc)
+	}
+	if got := doc.RunStats.Hosts; got.Up != 1 || got.Down != 1 || got.Total != 2 {
+		t.Errorf("runstats hosts = %+v", got)
+	}
+	if len(doc.Hosts) != 2 || doc.Hosts[1].Status.State != "down" {
+		t.Fatalf("hosts = %+v", doc.Hosts)
+	}
+
+	host := doc.Hosts[0]
+	if host.Address.Addr != "10.0.0.5" || host.Address.AddrType != "ipv4" || host.Hostnames != nil {
+		t.Errorf("address = %+v, hostnames = %+v", host.Address, host.Hostnames)
+	}
+	if host.EndTime-host.StartTime != 2 {
+		t.Errorf("host times = %d..%d, want 2s apart", host.StartTime, host.EndTime)
+	}
+
+	// 30 closed ports collapse; open and the single filtered port are listed.
+	if len(host.Ports.Extra) != 1 || host.Ports.Extra[0].State != "closed" || host.Ports.Extra[0].Count != 30 {
+		t.Errorf("extraports = %+v", host.Ports.Extra)
+	}
+	if len(host.Ports.Ports) != 3 {
+		t.Fatalf("ports = %+v", host.Ports.Ports)
+	}
+
+	ssh, filtered, https := host.Ports.Ports[0], host.Ports.Ports[1], host.Ports.Ports[2]
+	if ssh.PortID != 22 || ssh.State.State != "open" || ssh.Service.Product != "OpenSSH" || ssh.Service.Version != "8.9p1" {
+		t.Errorf("port 22 = %+v %+v", ssh, ssh.Service)
+	}
+	if filtered.PortID != 80 || filtered.State.State != "filtered" || filtered.State.Reason != "no-response" {
+		t.Errorf("port 80 = %+v", filtered)
+	}
+	if filtered.Service == nil || filtered.Service.Method != "table" || filtered.Service.Name != "http" {
+		t.Errorf("port 80 service = %+v, want http from the services table", filtered.Service)
+	}
+	if https.Service.Tunnel != "ssl" || https.Service.Product != "nginx" {
+		t.Errorf("port 443 service = %+v", https.Service)
+	}
+}
+
+func TestWriteGrepable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGrepable(&buf, testRun()); err != nil {
+		t.Fatalf("WriteGrepable() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"# netdiag 1.0.0 scan initiated Sun Mar  1 12:00:00 2026 as: netdiag scan 10.0.0.5 -p 22,80,443,1000-1029",
+		"Host: 10.0.0.5 ()\tStatus: Up",
+		"Host: 10.0.0.5 ()\tPorts: 22/open/tcp//ssh//OpenSSH_8.9p1/, 80/filtered/tcp//http///, " +
+			"443/open/tcp//ssl|http//nginx|1.18.0/\tIgnored State: closed (30)",
+		"Host: 10.0.0.6 ()\tStatus: Down",
+		"# netdiag done at Sun Mar  1 12:00:02 2026 -- 2 IP addresses (1 host up) scanned in 2.00 seconds",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d:\n got %q\nwant %q", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestWriteGrepableAllIgnored(t *testing.T) {
+	run := testRun()
+	data := run.Results[0].ScanData
+	data.OpenPorts, data.FilteredPorts, data.Services = nil, nil, nil
+
+	var buf bytes.Buffer
+	if err := WriteGrepable(&buf, run); err != nil {
+		t.Fatalf("WriteGrepable() error = %v", err)
+	}
+
+	want := "Host: 10.0.0.5 ()\tIgnored State: closed (30)\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("missing %q in:\n%s", want, buf.String())
+	}
+	if strings.Contains(buf.String(), "Ports:") {
+		t.Errorf("Ports field written for a host with no listed ports:\n%s", buf.String())
+	}
+}
+
+func TestSplitProductVersion(t *testing.T) {
+	tests := []struct {
+		in, product, version string
+	}{
+		{"nginx/1.18.0", "nginx", "1.18.0"},
+		{"OpenSSH_8.9p1 Ubuntu-3", "OpenSSH", "8.9p1 Ubuntu-3"},
+		{"vsFTPd 3.0.3", "vsFTPd", "3.0.3"},
+		{"cloudflare", "cloudflare", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		product, version := splitProductVersion(tt.in)
+		if product != tt.product || version != tt.version {
+			t.Errorf("splitProductVersion(%q) = %q, %q; want %q, %q", tt.in, product, version, tt.product, tt.version)
+		}
+	}
+}
+
+func TestHostAddress(t *testing.T) {
+	tests := []struct {
+		target, resolved         string
+		addr, addrType, hostname string
+	}{
+		{"10.0.0.5", "", "10.0.0.5", "ipv4", ""},
+		{"db.internal", "10.0.0.7", "10.0.0.7", "ipv4", "db.internal"},
+		{"db.internal", "2001:db8::7", "2001:db8::7", "ipv6", "db.internal"},
+		// Never scanned, so never resolved: reported as given.
+		{"db.internal", "", "db.internal", "ipv4", "db.internal"},
+	}
+	for _, tt := range tests {
+		addr, addrType, hostname := hostAddress(tt.target, tt.resolved)
+		if addr != tt.addr || addrType != tt.addrType || hostname != tt.hostname {
+			t.Errorf("hostAddress(%q, %q) = %q, %q, %q; want %q, %q, %q",
+				tt.target, tt.resolved, addr, addrType, hostname, tt.addr, tt.addrType, tt.hostname)
+		}
+	}
+}
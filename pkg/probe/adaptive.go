@@ -0,0 +1,210 @@
+package probe
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Bounds for adaptive scan timeouts, matching nmap's defaults.
+const (
+	MinScanTimeout = 100 * time.Millisecond
+	MaxScanTimeout = 10 * time.Second
+)
+
+// RTTEstimator derives a retransmission timeout from observed round-trip
+// times using the RFC 6298 smoothing nmap applies to scans:
+// timeout = srtt + 4*rttvar, clamped to [Min, Max]. Until the first sample
+// it returns Initial. It is safe for concurrent use.
+type RTTEstimator struct {
+	Initial time.Duration
+	Min     time.Duration
+	Max     time.Duration
+
+	mu      sync.Mutex
+	srtt    time.Duration
+	rttvar  time.Duration
+	samples int
+}
+
+// NewRTTEstimator returns an estimator starting at initial and bounded by
+// MinScanTimeout and MaxScanTimeout.
+func NewRTTEstimator(initial time.Duration) *RTTEstimator {
+	return &RTTEstimator{Initial: initial, Min: MinScanTimeout, Max: MaxScanTimeout}
+}
+
+// Observe folds one round-trip measurement into the estimate.
+func (e *RTTEstimator) Observe(rtt time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.samples == 0 {
+		e.srtt = rtt
+		e.rttvar = rtt / 2
+	} else {
+		delta := e.srtt - rtt
+		if delta < 0 {
+			delta = -delta
+		}
+		e.rttvar = (3*e.rttvar + delta) / 4
+		e.srtt = (7*e.srtt + rtt) / 8
+	}
+	e.samples++
+}
+
+// SRTT returns the smoothed round-trip time, or zero before any sample.
+func (e *RTTEstimator) SRTT() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.srtt
+}
+
+// Timeout returns the current timeout.
+func (e *RTTEstimator) Timeout() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.samples == 0 {
+		return e.Initial
+	}
+	return min(max(e.srtt+4*e.rttvar, e.Min), e.Max)
+}
+
+// congestionWindow limits in-flight probes, halving the limit when timeouts
+// spike and growing it back additively as answers arrive (AIMD).
+type congestionWindow struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    float64
+	floor    float64
+	ceiling  float64
+	inFlight int
+
+	// recent is a ring of the last outcomes; true means timed out.
+	recent  [congestionSample]bool
+	pos     int
+	filled  int
+	lastCut time.Time
+}
+
+const (
+	congestionSample = 20
+	// congestionSpike is the share of recent probes that must time out
+	// before the window is cut.
+	congestionSpike = 0.5
+)
+
+func newCongestionWindow(ceiling int) *congestionWindow {
+	w := &congestionWindow{
+		limit:   float64(ceiling),
+		floor:   float64(max(1, ceiling/10)),
+		ceiling: float64(ceiling),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+// acquire blocks until the number of in-flight probes is under the limit.
+func (w *congestionWindow) acquire(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		w.mu.Lock()
+		w.cond.Broadcast()
+		w.mu.Unlock()
+	})
+	defer stop()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.inFlight >= int(w.limit) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		w.cond.Wait()
+	}
+	w.inFlight++
+	return nil
+}
+
+// release records a probe's outcome. The window is cut at most once per
+// hold period so a single burst of drops does not collapse it.
+func (w *congestionWindow) release(timedOut bool, hold time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.inFlight--
+	w.recent[w.pos] = timedOut
+	w.pos = (w.pos + 1) % congestionSample
+	w.filled = min(w.filled+1, congestionSample)
+
+	if !timedOut {
+		w.limit = min(w.limit+1/w.limit, w.ceiling)
+	} else if w.filled >= congestionSample/2 && time.Since(w.lastCut) > hold {
+		timeouts := 0
+		for i := range w.filled {
+			if w.recent[i] {
+				timeouts++
+			}
+		}
+		if float64(timeouts)/float64(w.filled) > congestionSpike {
+			w.limit = max(w.limit/2, w.floor)
+			w.lastCut = time.Now()
+			w.filled, w.pos = 0, 0
+		}
+	}
+
+	w.cond.Broadcast()
+}
+
+// current returns the window size.
+func (w *congestionWindow) current() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return int(w.limit)
+}
+
+// RateLimiter paces events to at most a fixed number per second. One
+// limiter may be shared by several scanners to cap the total packet rate.
+// A nil *RateLimiter imposes no limit.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter returns a limiter allowing perSecond events per second, or
+// nil (unlimited) if perSecond is not positive.
+func NewRateLimiter(perSecond int) *RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+// Wait blocks until the next event may proceed or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
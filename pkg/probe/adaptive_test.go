@@ -0,0 +1,138 @@
+package probe
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRTTEstimator(t *testing.T) {
+	e := NewRTTEstimator(time.Second)
+	if got := e.Timeout(); got != time.Second {
+		t.Fatalf("initial Timeout() = %v, want 1s", got)
+	}
+
+	// First sample: srtt = rtt, rttvar = rtt/2, timeout = 3*rtt.
+	e.Observe(200 * time.Millisecond)
+	if got := e.Timeout(); got != 600*time.Millisecond {
+		t.Errorf("Timeout() after one sample = %v, want 600ms", got)
+	}
+
+	// A stable RTT shrinks the variance towards zero.
+	for range 50 {
+		e.Observe(200 * time.Millisecond)
+	}
+	if got := e.Timeout(); got < 200*time.Millisecond || got > 210*time.Millisecond {
+		t.Errorf("Timeout() after stable samples = %v, want ~200ms", got)
+	}
+
+	// Fast networks are clamped to the minimum.
+	fast := NewRTTEstimator(time.Second)
+	fast.Observe(time.Millisecond)
+	if got := fast.Timeout(); got != MinScanTimeout {
+		t.Errorf("Timeout() on a fast network = %v, want %v", got, MinScanTimeout)
+	}
+
+	// Slow networks are clamped to the maximum.
+	slow := NewRTTEstimator(time.Second)
+	slow.Observe(5 * time.Second)
+	if got := slow.Timeout(); got != MaxScanTimeout {
+		t.Errorf("Timeout() on a slow network = %v, want %v", got, MaxScanTimeout)
+	}
+}
+
+func TestCongestionWindow(t *testing.T) {
+	w := newCongestionWindow(100)
+	ctx := context.Background()
+
+	release := func(timedOut bool) {
+		if err := w.acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+		w.release(timedOut, 0)
+	}
+
+	// Scattered timeouts do not cut the window.
+	for i := range congestionSample {
+		release(i%4 == 0)
+	}
+	if got := w.current(); got != 100 {
+		t.Fatalf("window after scattered timeouts = %d, want 100", got)
+	}
+
+	// A spike halves it, down to the floor.
+	for range 10 * congestionSample {
+		release(true)
+	}
+	if got := w.current(); got != 10 {
+		t.Errorf("window after timeout spike = %d, want floor 10", got)
+	}
+
+	// Answers grow it back.
+	for range 500 {
+		release(false)
+	}
+	if got := w.current(); got <= 10 {
+		t.Errorf("window after answers = %d, want > 10", got)
+	}
+}
+
+func TestCongestionWindowBlocks(t *testing.T) {
+	w := newCongestionWindow(1)
+	if err := w.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := w.acquire(ctx); err == nil {
+		t.Fatal("acquire on a full window succeeded, want context error")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	if err := (*RateLimiter)(nil).Wait(context.Background()); err != nil {
+		t.Fatalf("nil limiter Wait() = %v", err)
+	}
+
+	l := NewRateLimiter(200)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 5 {
+				_ = l.Wait(context.Background())
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 20 events at 200/s span 19 intervals of 5ms (95ms); allow some slack.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("20 events took %v, want >= 90ms", elapsed)
+	}
+}
+
+func TestConnectScannerAdaptive(t *testing.T) {
+	s := &ConnectScanner{
+		Host:        "127.0.0.1",
+		Ports:       PortSet{{Start: 1, End: 100}},
+		Timeout:     time.Second,
+		Concurrency: 10,
+		Adaptive:    true,
+	}
+	res, err := s.Probe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := res.ScanData
+	if d.RTT <= 0 || d.AdaptiveTimeout != MinScanTimeout {
+		t.Errorf("RTT = %v, AdaptiveTimeout = %v, want RTT > 0 and timeout %v on loopback",
+			d.RTT, d.AdaptiveTimeout, MinScanTimeout)
+	}
+}
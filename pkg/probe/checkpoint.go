@@ -0,0 +1,201 @@
+package probe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// checkpointVersion is bumped when the file format changes incompatibly.
+const checkpointVersion = 1
+
+// Checkpoint records scan progress so an interrupted scan can be resumed.
+// Per-port outcomes are recorded as they arrive; hosts that finish keep
+// their whole result. It is safe for concurrent use.
+type Checkpoint struct {
+	Version int       `json:"version"`
+	Updated time.Time `json:"updated"`
+	// Targets, Ports and Method describe the scan so it can be resumed
+	// without repeating the command line.
+	Targets []string                   `json:"targets"`
+	Ports   string                     `json:"ports"`
+	Method  string                     `json:"method"`
+	Hosts   map[string]*HostCheckpoint `json:"hosts"`
+
+	mu   sync.Mutex
+	path string
+}
+
+// HostCheckpoint is the saved state of one host.
+type HostCheckpoint struct {
+	// Ports maps each state to the ports already seen in it.
+	Ports map[PortState][]int `json:"ports,omitempty"`
+	// Result is set once the host's scan has finished.
+	Result *Result `json:"result,omitempty"`
+}
+
+// NewCheckpoint returns an empty checkpoint that saves to path.
+func NewCheckpoint(path string, targets []string, ports, method string) *Checkpoint {
+	return &Checkpoint{
+		Version: checkpointVersion,
+		Targets: targets,
+		Ports:   ports,
+		Method:  method,
+		Hosts:   make(map[string]*HostCheckpoint),
+		path:    path,
+	}
+}
+
+// LoadCheckpoint reads a checkpoint saved by Save. Later saves go back to
+// the same path.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	if c.Version != checkpointVersion {
+		return nil, fmt.Errorf("checkpoint %s has version %d, want %d", path, c.Version, checkpointVersion)
+	}
+	if c.Hosts == nil {
+		c.Hosts = make(map[string]*HostCheckpoint)
+	}
+	c.path = path
+	return &c, nil
+}
+
+// SaveTo directs later saves to path.
+func (c *Checkpoint) SaveTo(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.path = path
+}
+
+// host returns the entry for host, creating it. The caller holds c.mu.
+func (c *Checkpoint) host(host string) *HostCheckpoint {
+	h, ok := c.Hosts[host]
+	if !ok {
+		h = &HostCheckpoint{Ports: make(map[PortState][]int)}
+		c.Hosts[host] = h
+	}
+	if h.Ports == nil {
+		h.Ports = make(map[PortState][]int)
+	}
+	return h
+}
+
+// Record notes the outcome of one port. Its signature matches a scanner's
+// Progress callback via ScanProgress.Port and ScanProgress.State.
+func (c *Checkpoint) Record(host string, port int, state PortState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.host(host)
+	h.Ports[state] = append(h.Ports[state], port)
+}
+
+// Complete stores the finished result for a host; resumed scans reuse it.
+func (c *Checkpoint) Complete(result Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.host(result.Target)
+	h.Result = &result
+	h.Ports = nil
+}
+
+// Completed returns the saved result for host if its scan had finished.
+func (c *Checkpoint) Completed(host string) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if h, ok := c.Hosts[host]; ok && h.Result != nil {
+		return *h.Result, true
+	}
+	return Result{}, false
+}
+
+// Remaining returns the ports of set that have no recorded outcome for host.
+func (c *Checkpoint) Remaining(host string, set PortSet) PortSet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.Hosts[host]
+	if !ok {
+		return set
+	}
+	var done []int
+	for _, ports := range h.Ports {
+		done = append(done, ports...)
+	}
+	return set.Subtract(NewPortSet(done...))
+}
+
+// Merge folds the outcomes recorded for host into data, which covers only
+// the ports scanned after resuming, and counts them in TotalPorts.
+func (c *Checkpoint) Merge(host string, data *ScanData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.Hosts[host]
+	if !ok || data == nil {
+		return
+	}
+
+	seen := make(map[int]bool)
+	for _, state := range []PortState{PortOpen, PortClosed, PortFiltered, PortUnreachable, PortOpenFiltered} {
+		for _, p := range data.Ports(state) {
+			seen[p] = true
+		}
+	}
+
+	for state, ports := range h.Ports {
+		for _, p := range ports {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			data.add(p, state)
+			data.TotalPorts++
+			switch state {
+			case PortClosed:
+				data.Refused++
+			case PortFiltered, PortOpenFiltered:
+				data.Timeouts++
+			}
+		}
+	}
+	data.sortPorts()
+}
+
+// Save writes the checkpoint atomically, so an interrupted save never
+// leaves a truncated file behind.
+func (c *Checkpoint) Save() error {
+	c.mu.Lock()
+	c.Updated = time.Now()
+	data, err := json.Marshal(c)
+	path := c.path
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".netdiag-checkpoint-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,96 @@
+package probe
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	cp := NewCheckpoint(path, []string{"10.0.0.0/30"}, "1-10", "connect")
+
+	cp.Record("10.0.0.1", 1, PortOpen)
+	cp.Record("10.0.0.1", 2, PortClosed)
+	cp.Record("10.0.0.1", 3, PortFiltered)
+	cp.Complete(Result{Target: "10.0.0.2", ProbeType: "scan", Success: true, ScanData: &ScanData{OpenPorts: []int{22}}})
+
+	if err := cp.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error = %v", err)
+	}
+	if loaded.Ports != "1-10" || loaded.Method != "connect" || !reflect.DeepEqual(loaded.Targets, []string{"10.0.0.0/30"}) {
+		t.Errorf("loaded scan description = %q %q %v", loaded.Ports, loaded.Method, loaded.Targets)
+	}
+
+	if r, ok := loaded.Completed("10.0.0.2"); !ok || !reflect.DeepEqual(r.ScanData.OpenPorts, []int{22}) {
+		t.Errorf("Completed(10.0.0.2) = %+v, %v", r, ok)
+	}
+	if _, ok := loaded.Completed("10.0.0.1"); ok {
+		t.Error("Completed(10.0.0.1) = true for a partial host")
+	}
+
+	set := PortSet{{Start: 1, End: 10}}
+	remaining := loaded.Remaining("10.0.0.1", set)
+	if got, want := remaining.Slice(), []int{4, 5, 6, 7, 8, 9, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Remaining() = %v, want %v", got, want)
+	}
+	if got := loaded.Remaining("10.0.0.3", set); got.Len() != 10 {
+		t.Errorf("Remaining() for an unseen host has %d ports, want 10", got.Len())
+	}
+
+	// The resumed scan covered 4-10 and found 8 open.
+	data := &ScanData{TotalPorts: 7, OpenPorts: []int{8}, ClosedPorts: []int{4, 5, 6, 7, 9, 10}, Refused: 6}
+	loaded.Merge("10.0.0.1", data)
+
+	if data.TotalPorts != 10 {
+		t.Errorf("TotalPorts = %d, want 10", data.TotalPorts)
+	}
+	if !reflect.DeepEqual(data.OpenPorts, []int{1, 8}) {
+		t.Errorf("OpenPorts = %v, want [1 8]", data.OpenPorts)
+	}
+	if !reflect.DeepEqual(data.ClosedPorts, []int{2, 4, 5, 6, 7, 9, 10}) {
+		t.Errorf("ClosedPorts = %v", data.ClosedPorts)
+	}
+	if !reflect.DeepEqual(data.FilteredPorts, []int{3}) {
+		t.Errorf("FilteredPorts = %v, want [3]", data.FilteredPorts)
+	}
+	if data.Refused != 7 || data.Timeouts != 1 {
+		t.Errorf("Refused, Timeouts = %d, %d, want 7, 1", data.Refused, data.Timeouts)
+	}
+}
+
+func TestCheckpointMergeSkipsRescannedPorts(t *testing.T) {
+	cp := NewCheckpoint(filepath.Join(t.TempDir(), "state.json"), nil, "1-2", "connect")
+	cp.Record("h", 1, PortFiltered)
+	cp.Record("h", 2, PortOpen)
+
+	// Port 1 was scanned again after resuming and is now open.
+	data := &ScanData{TotalPorts: 1, OpenPorts: []int{1}}
+	cp.Merge("h", data)
+
+	if !reflect.DeepEqual(data.OpenPorts, []int{1, 2}) || len(data.FilteredPorts) != 0 || data.TotalPorts != 2 {
+		t.Errorf("Merge() = %+v", data)
+	}
+}
+
+func TestLoadCheckpointErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := LoadCheckpoint(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadCheckpoint() on a missing file: want error")
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"version": 99}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCheckpoint(bad); err == nil {
+		t.Error("LoadCheckpoint() with an unknown version: want error")
+	}
+}
@@ -11,8 +11,11 @@ import (
 type DigProber struct {
 	Host       string
 	Server     string // Optional custom DNS server (e.g., "8.8.8.8")
-	RecordType string // "A", "MX", "TXT", "NS", "CNAME"
+	RecordType string // "A", "AAAA", "MX", "TXT", "NS", "CNAME"
 	Timeout    time.Duration
+	// Family restricts how the DNS server is reached, not which records
+	// are looked up.
+	Family Family
 }
 
 func (d *DigProber) Type() string {
@@ -23,29 +26,32 @@ func (d *DigProber) Probe(ctx context.Context) (Result, error) {
 
 	start := time.Now()
 
-	var resolver *net.Resolver
+	resolver := net.DefaultResolver
 
-	// Custom DNS server support
-	if d.Server != "" {
+	// Custom DNS server and address family support
+	if d.Server != "" || d.Family != FamilyAny {
 
-		dialer := &net.Dialer{
-			Timeout: d.Timeout,
+		dialer := &familyDialer{
+			Dialer: net.Dialer{Timeout: d.Timeout},
+			family: d.Family,
 		}
 
 		serverAddr := d.Server
-		if !strings.Contains(serverAddr, ":") {
-			serverAddr = net.JoinHostPort(serverAddr, "53")
+		if serverAddr != "" {
+			if _, _, err := net.SplitHostPort(serverAddr); err != nil {
+				serverAddr = net.JoinHostPort(serverAddr, "53")
+			}
 		}
 
 		resolver = &net.Resolver{
 			PreferGo: true,
 			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-				return dialer.DialContext(ctx, network, serverAddr)
+				if serverAddr != "" {
+					address = serverAddr
+				}
+				return dialer.DialContext(ctx, network, address)
 			},
 		}
-
-	} else {
-		resolver = net.DefaultResolver
 	}
 
 	recordType := strings.ToUpper(d.RecordType)
@@ -58,17 +64,19 @@ func (d *DigProber) Probe(ctx context.Context) (Result, error) {
 
 	switch recordType {
 
-	case "A":
-		var ips []net.IPAddr
-		ips, err = resolver.LookupIPAddr(ctx, d.Host)
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		var ips []net.IP
+		ips, err = resolver.LookupIP(ctx, network, d.Host)
 		if err == nil {
 			for _, ip := range ips {
-				if ip.IP.To4() != nil {
-					records = append(records, DNSRecord{
-						Type:  "A",
-						Value: ip.IP.String(),
-					})
-				}
+				records = append(records, DNSRecord{
+					Type:  recordType,
+					Value: ip.String(),
+				})
 			}
 		}
 
@@ -4,18 +4,30 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/netip"
+	"os"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	probing "github.com/prometheus-community/pro-bing"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
 )
 
 type DiscoverProber struct {
 	Timeout time.Duration
+	// Family selects an IPv4 ping sweep or IPv6 neighbor discovery.
+	// FamilyAny sweeps IPv4 and falls back to IPv6 on hosts without an
+	// IPv4 network.
+	Family Family
 }
 
+// maxSweepBits caps the IPv4 sweep at a /24 around the local address, so a
+// host on a /16 does not ping 65k addresses.
+const maxSweepBits = 8
+
 func (d *DiscoverProber) Type() string {
 	return "discover"
 }
@@ -24,7 +36,29 @@ func (d *DiscoverProber) Probe(ctx context.Context) (Result, error) {
 
 	start := time.Now()
 
-	localIP, prefix, err := getLocalIPPrefix()
+	var (
+		localIP net.IP
+		prefix  netip.Prefix
+		devices []DiscoverDevice
+		mode    PingMode
+		err     error
+	)
+
+	if d.Family != FamilyIPv6 {
+		localIP, prefix, err = localIPv4Prefix()
+		if err == nil {
+			mode = DetectPingMode(localIP)
+			devices, err = d.sweepIPv4(ctx, localIP, prefix, mode)
+		}
+	}
+	if d.Family == FamilyIPv6 || (d.Family == FamilyAny && localIP == nil) {
+		var ifi *net.Interface
+		ifi, localIP, prefix, err = localIPv6Prefix()
+		if err == nil {
+			devices, mode, err = d.discoverIPv6(ctx, ifi)
+		}
+	}
+
 	if err != nil {
 		return Result{
 			TimeStamp: time.Now(),
@@ -36,17 +70,58 @@ func (d *DiscoverProber) Probe(ctx context.Context) (Result, error) {
 		}, nil
 	}
 
+	sort.Slice(devices, func(i, j int) bool {
+		a, errA := netip.ParseAddr(devices[i].IP)
+		b, errB := netip.ParseAddr(devices[j].IP)
+		if errA != nil || errB != nil {
+			return devices[i].IP < devices[j].IP
+		}
+		return a.Less(b)
+	})
+
+	data := &DiscoverData{
+		LocalIP: localIP.String(),
+		Prefix:  prefix.String(),
+		Devices: devices,
+		Mode:    mode,
+	}
+
+	severity := SeverityOK
+	message := fmt.Sprintf("Scan complete. Found %d devices.", len(devices))
+
+	if len(devices) == 0 {
+		severity = SeverityWarning
+		message = "No devices found"
+	}
+
+	return Result{
+		TimeStamp:    time.Now(),
+		ProbeType:    "discover",
+		Target:       prefix.String(),
+		DiscoverData: data,
+		Success:      true,
+		Severity:     severity,
+		Message:      message,
+		Latency:      time.Since(start),
+	}, nil
+}
+
+// sweepIPv4 pings every address in prefix except localIP, in the given mode.
+func (d *DiscoverProber) sweepIPv4(ctx context.Context, localIP net.IP, prefix netip.Prefix, mode PingMode) ([]DiscoverDevice, error) {
+	targets, err := ExpandTargets([]string{prefix.String()})
+	if err != nil {
+		return nil, err
+	}
+
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	sem := make(chan struct{}, 50)
 
 	var devices []DiscoverDevice
 
-	for i := 1; i < 255; i++ {
+	for _, targetIP := range targets {
 
-		targetIP := fmt.Sprintf("%s.%d", prefix, i)
-
-		if targetIP == localIP {
+		if targetIP == localIP.String() {
 			continue
 		}
 
@@ -61,21 +136,8 @@ func (d *DiscoverProber) Probe(ctx context.Context) (Result, error) {
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			pinger, err := probing.NewPinger(ip)
-			if err != nil {
-				return
-			}
-
-			pinger.Count = 1
-			pinger.Timeout = d.Timeout
-			pinger.SetPrivileged(true)
-
-			if err := pinger.RunWithContext(ctx); err != nil {
-				return
-			}
-
-			stats := pinger.Statistics()
-			if stats.PacketsRecv > 0 {
+			latency, ok := d.ping(ctx, ip, mode)
+			if ok {
 
 				host := resolveHostname(ip)
 
@@ -83,7 +145,7 @@ func (d *DiscoverProber) Probe(ctx context.Context) (Result, error) {
 				devices = append(devices, DiscoverDevice{
 					IP:       ip,
 					HostName: host,
-					Latency:  stats.AvgRtt,
+					Latency:  latency,
 				})
 				mu.Unlock()
 			}
@@ -92,61 +154,336 @@ func (d *DiscoverProber) Probe(ctx context.Context) (Result, error) {
 	}
 
 	wg.Wait()
+	return devices, nil
+}
 
-	sort.Slice(devices, func(i, j int) bool {
-		return devices[i].IP < devices[j].IP
-	})
+// ping sends one ping to ip and reports whether it was answered.
+func (d *DiscoverProber) ping(ctx context.Context, ip string, mode PingMode) (time.Duration, bool) {
+	if mode == PingModeTCP {
+		return tcpPingOnce(ctx, net.ParseIP(ip), d.Timeout)
+	}
 
-	data := &DiscoverData{
-		LocalIP: localIP,
-		Prefix:  prefix,
-		Devices: devices,
+	pinger, err := probing.NewPinger(ip)
+	if err != nil {
+		return 0, false
 	}
 
-	severity := SeverityOK
-	message := fmt.Sprintf("Scan complete. Found %d devices.", len(devices))
+	pinger.Count = 1
+	pinger.Timeout = d.Timeout
+	pinger.SetPrivileged(mode == PingModePrivileged)
 
-	if len(devices) == 0 {
-		severity = SeverityWarning
-		message = "No devices found"
+	if err := pinger.RunWithContext(ctx); err != nil {
+		return 0, false
 	}
 
-	return Result{
-		TimeStamp:    time.Now(),
-		ProbeType:    "discover",
-		Target:       prefix,
-		DiscoverData: data,
-		Success:      true,
-		Severity:     severity,
-		Message:      message,
-		Latency:      time.Since(start),
-	}, nil
+	stats := pinger.Statistics()
+	return stats.AvgRtt, stats.PacketsRecv > 0
+}
+
+// allNodes is the link-local all-nodes multicast group.
+var allNodes = net.ParseIP("ff02::1")
+
+// neighbor is an entry in the kernel's IPv6 neighbor table.
+type neighbor struct {
+	IP    net.IP
+	State uint16
+}
+
+// Neighbor unreachability detection (NUD) states from linux/neighbour.h.
+const (
+	nudReachable = 0x02
+	nudStale     = 0x04
+	nudDelay     = 0x08
+	nudProbe     = 0x10
+	nudPermanent = 0x80
+)
+
+// discoverIPv6 finds IPv6 neighbors on ifi. A /64 cannot be swept, so it
+// combines what neighbor discovery (NDP) can see instead:
+//   - one ICMPv6 echo to the all-nodes group, which every host on the link
+//     answers from its link-local address;
+//   - a Neighbor Solicitation for each address in the kernel's neighbor
+//     table, whose advertisements confirm hosts it has seen before,
+//     including their global addresses;
+//   - the neighbor table itself, read again once the replies are in.
+//
+// Soliciting needs a raw socket. Without one the echo goes out over an
+// unprivileged datagram socket and table entries are listed unconfirmed;
+// with neither, the table is all there is.
+func (d *DiscoverProber) discoverIPv6(ctx context.Context, ifi *net.Interface) ([]DiscoverDevice, PingMode, error) {
+	known, tableErr := ipv6Neighbors(ifi)
+
+	mode := PingModePrivileged
+	conn, err := icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		mode = PingModeUnprivileged
+		conn, err = icmp.ListenPacket("udp6", "::")
+	}
+	if err != nil {
+		if tableErr != nil {
+			return nil, "", fmt.Errorf("IPv6 discovery needs an ICMPv6 socket (root, CAP_NET_RAW or net.ipv4.ping_group_range) or the neighbor table: %w", tableErr)
+		}
+		mode = PingModeNeighbors
+	}
+
+	seen := make(map[string]bool)
+	var devices []DiscoverDevice
+	add := func(ip net.IP, rtt time.Duration) {
+		addr := neighborAddr(ip, ifi)
+		if seen[addr] {
+			return
+		}
+		seen[addr] = true
+		devices = append(devices, DiscoverDevice{IP: addr, Latency: rtt})
+	}
+
+	if conn != nil {
+		defer func() { _ = conn.Close() }()
+		if err := d.solicitIPv6(ctx, conn, ifi, mode, known, add); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Entries the kernel has confirmed on its own. Unless we solicited
+	// every known neighbor, stale entries are the best evidence there is.
+	confirmed := uint16(nudReachable | nudPermanent)
+	if mode != PingModePrivileged {
+		confirmed |= nudStale | nudDelay | nudProbe
+	}
+
+	if after, err := ipv6Neighbors(ifi); err == nil {
+		own := localAddrs()
+		for _, n := range after {
+			if n.State&confirmed != 0 && !own[n.IP.String()] {
+				add(n.IP, 0)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range devices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			devices[i].HostName = resolveHostname(devices[i].IP)
+		}()
+	}
+	wg.Wait()
+
+	return devices, mode, nil
+}
+
+// solicitIPv6 sends the all-nodes echo, and with a raw socket a Neighbor
+// Solicitation for each known neighbor, then calls add for every host that
+// answers before the timeout.
+func (d *DiscoverProber) solicitIPv6(ctx context.Context, conn *icmp.PacketConn, ifi *net.Interface, mode PingMode, known []neighbor, add func(net.IP, time.Duration)) error {
+	privileged := mode == PingModePrivileged
+
+	p := conn.IPv6PacketConn()
+	if err := p.SetMulticastInterface(ifi); err != nil {
+		return err
+	}
+	// NDP messages must arrive with a hop limit of 255; link-local
+	// multicast never leaves the link anyway.
+	_ = p.SetMulticastHopLimit(255)
+
+	if privileged {
+		var filter ipv6.ICMPFilter
+		filter.SetAll(true)
+		filter.Accept(ipv6.ICMPTypeEchoReply)
+		filter.Accept(ipv6.ICMPTypeNeighborAdvertisement)
+		_ = p.SetICMPFilter(&filter)
+	}
+
+	id := os.Getpid() & 0xffff
+	echo, err := (&icmp.Message{
+		Type: ipv6.ICMPTypeEchoRequest,
+		Body: &icmp.Echo{ID: id, Seq: 1, Data: []byte("NETDIAG_DISCOVER")},
+	}).Marshal(nil)
+	if err != nil {
+		return err
+	}
+
+	// A datagram socket takes UDP addresses and sets the echo ID itself.
+	dst := func(ip net.IP) net.Addr {
+		if privileged {
+			return &net.IPAddr{IP: ip, Zone: ifi.Name}
+		}
+		return &net.UDPAddr{IP: ip, Zone: ifi.Name}
+	}
+
+	own := localAddrs()
+	sent := time.Now()
+	if _, err := conn.WriteTo(echo, dst(allNodes)); err != nil {
+		return fmt.Errorf("failed to send to %s: %w", allNodes, err)
+	}
+	if privileged {
+		for _, n := range known {
+			if own[n.IP.String()] {
+				continue
+			}
+			ns, err := (&icmp.Message{
+				Type: ipv6.ICMPTypeNeighborSolicitation,
+				Body: &icmp.RawBody{Data: neighborSolicitation(n.IP, ifi.HardwareAddr)},
+			}).Marshal(nil)
+			if err != nil {
+				return err
+			}
+			_, _ = conn.WriteTo(ns, dst(solicitedNode(n.IP)))
+		}
+	}
+
+	deadline := sent.Add(d.Timeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	_ = conn.SetReadDeadline(deadline)
+
+	buf := make([]byte, 1500)
+	for ctx.Err() == nil {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		rtt := time.Since(sent)
+
+		reply, err := icmp.ParseMessage(58, buf[:n])
+		if err != nil {
+			continue
+		}
+
+		var ip net.IP
+		switch reply.Type {
+		case ipv6.ICMPTypeEchoReply:
+			if echo, ok := reply.Body.(*icmp.Echo); !ok || (privileged && echo.ID != id) {
+				continue
+			}
+			switch addr := peer.(type) {
+			case *net.IPAddr:
+				ip = addr.IP
+			case *net.UDPAddr:
+				ip = addr.IP
+			}
+		case ipv6.ICMPTypeNeighborAdvertisement:
+			// Reserved flags, then the address being advertised.
+			if body, ok := reply.Body.(*icmp.RawBody); ok && len(body.Data) >= 4+net.IPv6len {
+				ip = net.IP(body.Data[4 : 4+net.IPv6len])
+			}
+		}
+		if ip == nil || own[ip.String()] {
+			continue
+		}
+		add(ip, rtt)
+	}
+	return nil
+}
+
+// neighborSolicitation builds the body of a Neighbor Solicitation for
+// target (RFC 4861 4.3), with our link-layer address when there is one so
+// the target can answer without soliciting us first.
+func neighborSolicitation(target net.IP, lladdr net.HardwareAddr) []byte {
+	body := make([]byte, 4, 4+net.IPv6len+8)
+	body = append(body, target.To16()...)
+	if len(lladdr) == 6 {
+		// Source link-layer address option: type 1, length in 8-byte units.
+		body = append(body, 1, 1)
+		body = append(body, lladdr...)
+	}
+	return body
 }
 
-func getLocalIPPrefix() (string, string, error) {
+// solicitedNode returns the solicited-node multicast group of ip, the
+// group a Neighbor Solicitation for ip is sent to.
+func solicitedNode(ip net.IP) net.IP {
+	group := net.ParseIP("ff02::1:ff00:0")
+	copy(group[13:], ip.To16()[13:])
+	return group
+}
+
+// neighborAddr formats ip as discovery reports it: link-local addresses
+// carry the interface as their zone, since they are ambiguous without it.
+func neighborAddr(ip net.IP, ifi *net.Interface) string {
+	if ip.IsLinkLocalUnicast() {
+		return (&net.IPAddr{IP: ip, Zone: ifi.Name}).String()
+	}
+	return ip.String()
+}
+
+// localIPv4Prefix returns the first non-loopback, non-link-local IPv4
+// address and the network to sweep, capped at maxSweepBits host bits.
+func localIPv4Prefix() (net.IP, netip.Prefix, error) {
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
-		return "", "", err
+		return nil, netip.Prefix{}, err
 	}
 
 	for _, address := range addrs {
-		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				ip := ipnet.IP.String()
-
-				if strings.HasPrefix(ip, "169.254") {
-					continue
-				}
-
-				lastDot := strings.LastIndex(ip, ".")
-				if lastDot != -1 {
-					return ip, ip[:lastDot], nil
-				}
+		ipnet, ok := address.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() || ipnet.IP.To4() == nil {
+			continue
+		}
+
+		addr, _ := netip.AddrFromSlice(ipnet.IP.To4())
+		ones, _ := ipnet.Mask.Size()
+		ones = max(ones, 32-maxSweepBits)
+		return ipnet.IP.To4(), netip.PrefixFrom(addr, ones).Masked(), nil
+	}
+
+	return nil, netip.Prefix{}, fmt.Errorf("no active local IPv4 address found")
+}
+
+// localIPv6Prefix returns the first multicast-capable interface with IPv6,
+// its preferred address (global over link-local) and that address's prefix.
+func localIPv6Prefix() (*net.Interface, net.IP, netip.Prefix, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, nil, netip.Prefix{}, err
+	}
+
+	for i := range ifaces {
+		ifi := &ifaces[i]
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagLoopback != 0 || ifi.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			continue
+		}
+
+		var best *net.IPNet
+		for _, address := range addrs {
+			ipnet, ok := address.(*net.IPNet)
+			if !ok || ipnet.IP.To4() != nil {
+				continue
+			}
+			if best == nil || (best.IP.IsLinkLocalUnicast() && ipnet.IP.IsGlobalUnicast()) {
+				best = ipnet
 			}
 		}
+		if best == nil {
+			continue
+		}
+
+		addr, _ := netip.AddrFromSlice(best.IP)
+		ones, _ := best.Mask.Size()
+		return ifi, best.IP, netip.PrefixFrom(addr, ones).Masked(), nil
 	}
 
-	return "", "", fmt.Errorf("no active local IP found")
+	return nil, nil, netip.Prefix{}, fmt.Errorf("no interface with an IPv6 address found")
+}
+
+// localAddrs returns every address assigned to this host, so discovery can
+// skip its own replies.
+func localAddrs() map[string]bool {
+	own := make(map[string]bool)
+	addrs, _ := net.InterfaceAddrs()
+	for _, address := range addrs {
+		if ipnet, ok := address.(*net.IPNet); ok {
+			own[ipnet.IP.String()] = true
+		}
+	}
+	return own
 }
 
 func resolveHostname(ip string) string {
@@ -0,0 +1,35 @@
+package probe
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestSolicitedNode(t *testing.T) {
+	got := solicitedNode(net.ParseIP("fd00::12:3456:789a"))
+	if want := net.ParseIP("ff02::1:ff56:789a"); !got.Equal(want) {
+		t.Errorf("solicitedNode() = %v, want %v", got, want)
+	}
+}
+
+func TestNeighborSolicitation(t *testing.T) {
+	target := net.ParseIP("fe80::1")
+	mac := net.HardwareAddr{0x02, 0, 0, 0, 0, 0x01}
+
+	body := neighborSolicitation(target, mac)
+	if len(body) != 4+16+8 {
+		t.Fatalf("len = %d, want 28", len(body))
+	}
+	if !net.IP(body[4:20]).Equal(target) {
+		t.Errorf("target = %v, want %v", net.IP(body[4:20]), target)
+	}
+	if body[20] != 1 || body[21] != 1 || !bytes.Equal(body[22:], mac) {
+		t.Errorf("source link-layer option = %x", body[20:])
+	}
+
+	// Without a MAC (e.g. on a tunnel) the option is left out.
+	if body := neighborSolicitation(target, nil); len(body) != 20 {
+		t.Errorf("len without lladdr = %d, want 20", len(body))
+	}
+}
@@ -0,0 +1,97 @@
+package probe
+
+import (
+	"strings"
+	"time"
+)
+
+// ScanDrift describes how a scan differs from an earlier baseline scan of
+// the same host.
+type ScanDrift struct {
+	// Baseline is when the baseline scan ran.
+	Baseline time.Time `json:"baseline"`
+	// Opened lists ports that are open now but were not before; Closed lists
+	// ports that were open before but no longer are.
+	Opened   []PortChange    `json:"opened,omitempty"`
+	Closed   []PortChange    `json:"closed,omitempty"`
+	Services []ServiceChange `json:"services,omitempty"`
+}
+
+// PortChange is a port whose state differs from the baseline. Before is
+// empty when the baseline did not record the port.
+type PortChange struct {
+	Port   int       `json:"port"`
+	Before PortState `json:"before,omitempty"`
+	After  PortState `json:"after"`
+}
+
+// ServiceChange is an open port whose detected service or version differs
+// from the baseline.
+type ServiceChange struct {
+	Port   int    `json:"port"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Drifted reports whether anything changed.
+func (d *ScanDrift) Drifted() bool {
+	return d != nil && len(d.Opened)+len(d.Closed)+len(d.Services) > 0
+}
+
+// CompareScans diffs current against baseline. Only ports the current scan
+// covered can count as closed, so scanning a narrower range than the
+// baseline does not report drift; an open port the baseline never recorded
+// does count as opened. Services are compared only where both scans ran
+// service detection on the port.
+func CompareScans(baseline, current Result) *ScanDrift {
+	drift := &ScanDrift{Baseline: baseline.TimeStamp}
+	if baseline.ScanData == nil || current.ScanData == nil {
+		return drift
+	}
+
+	before := baseline.ScanData.states()
+	after := current.ScanData.states()
+
+	for _, port := range current.ScanData.OpenPorts {
+		if state := before[port]; state != PortOpen {
+			drift.Opened = append(drift.Opened, PortChange{Port: port, Before: state, After: PortOpen})
+		}
+	}
+	for _, port := range baseline.ScanData.OpenPorts {
+		if state, ok := after[port]; ok && state != PortOpen {
+			drift.Closed = append(drift.Closed, PortChange{Port: port, Before: PortOpen, After: state})
+		}
+	}
+
+	previous := make(map[int]ServiceInfo, len(baseline.ScanData.Services))
+	for _, s := range baseline.ScanData.Services {
+		previous[s.Port] = s
+	}
+	for _, s := range current.ScanData.Services {
+		old, ok := previous[s.Port]
+		if !ok {
+			continue
+		}
+		if was, now := old.describe(), s.describe(); was != now {
+			drift.Services = append(drift.Services, ServiceChange{Port: s.Port, Before: was, After: now})
+		}
+	}
+
+	return drift
+}
+
+// states maps every recorded port to its state.
+func (d *ScanData) states() map[int]PortState {
+	states := make(map[int]PortState, d.TotalPorts)
+	for _, state := range []PortState{PortOpen, PortClosed, PortFiltered, PortUnreachable, PortOpenFiltered} {
+		for _, p := range d.Ports(state) {
+			states[p] = state
+		}
+	}
+	return states
+}
+
+// describe renders a service as "name version" for comparison and display.
+func (s ServiceInfo) describe() string {
+	return strings.TrimSpace(s.Service + " " + s.Version)
+}
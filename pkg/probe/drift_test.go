@@ -0,0 +1,86 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompareScans(t *testing.T) {
+	when := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	baseline := Result{
+		TimeStamp: when,
+		ScanData: &ScanData{
+			OpenPorts:     []int{22, 80, 443},
+			ClosedPorts:   []int{6379},
+			FilteredPorts: []int{8080},
+			Services: []ServiceInfo{
+				{Port: 22, Service: "ssh", Version: "OpenSSH_8.9p1"},
+				{Port: 443, Service: "http", Version: "nginx/1.18.0"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		current *ScanData
+		want    *ScanDrift
+	}{
+		{
+			name: "Unchanged",
+			current: &ScanData{
+				OpenPorts:   []int{22, 80, 443},
+				ClosedPorts: []int{6379, 8080},
+			},
+			want: &ScanDrift{Baseline: when},
+		},
+		{
+			name: "Opened, closed and changed",
+			current: &ScanData{
+				OpenPorts:     []int{22, 443, 6379, 9000},
+				FilteredPorts: []int{80},
+				Services: []ServiceInfo{
+					{Port: 22, Service: "ssh", Version: "OpenSSH_9.6p1"},
+					{Port: 443, Service: "http", Version: "nginx/1.18.0"},
+					{Port: 6379, Service: "redis"},
+				},
+			},
+			want: &ScanDrift{
+				Baseline: when,
+				Opened: []PortChange{
+					{Port: 6379, Before: PortClosed, After: PortOpen},
+					{Port: 9000, After: PortOpen},
+				},
+				Closed:   []PortChange{{Port: 80, Before: PortOpen, After: PortFiltered}},
+				Services: []ServiceChange{{Port: 22, Before: "ssh OpenSSH_8.9p1", After: "ssh OpenSSH_9.6p1"}},
+			},
+		},
+		{
+			name:    "Narrower scan is not drift",
+			current: &ScanData{OpenPorts: []int{22}},
+			want:    &ScanDrift{Baseline: when},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareScans(baseline, Result{ScanData: tt.current})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareScans() = %+v, want %+v", got, tt.want)
+			}
+			if got.Drifted() != tt.want.Drifted() {
+				t.Errorf("Drifted() = %v", got.Drifted())
+			}
+		})
+	}
+}
+
+func TestScanDriftDrifted(t *testing.T) {
+	var none *ScanDrift
+	if none.Drifted() {
+		t.Error("nil drift reports Drifted()")
+	}
+	if !(&ScanDrift{Closed: []PortChange{{Port: 22}}}).Drifted() {
+		t.Error("a closed port is not reported as drift")
+	}
+}
@@ -0,0 +1,86 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Family selects the IP version a probe uses.
+type Family int
+
+const (
+	// FamilyAny uses whatever a name resolves to, preferring IPv4.
+	FamilyAny Family = iota
+	// FamilyIPv4 restricts a probe to IPv4.
+	FamilyIPv4
+	// FamilyIPv6 restricts a probe to IPv6.
+	FamilyIPv6
+)
+
+func (f Family) String() string {
+	switch f {
+	case FamilyIPv4:
+		return "ipv4"
+	case FamilyIPv6:
+		return "ipv6"
+	default:
+		return "any"
+	}
+}
+
+// Network restricts a Go network name to the family: "tcp" becomes "tcp4"
+// or "tcp6". Names that already carry a version are returned unchanged.
+func (f Family) Network(network string) string {
+	if f == FamilyAny || strings.HasSuffix(network, "4") || strings.HasSuffix(network, "6") {
+		return network
+	}
+	if f == FamilyIPv4 {
+		return network + "4"
+	}
+	return network + "6"
+}
+
+// ResolveIP returns an address of host in family f. For FamilyAny an IPv4
+// address is preferred when host has both.
+func ResolveIP(ctx context.Context, host string, f Family) (net.IP, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var v6 net.IP
+	for _, a := range addrs {
+		if ip4 := a.IP.To4(); ip4 != nil {
+			if f != FamilyIPv6 {
+				return ip4, nil
+			}
+		} else if v6 == nil {
+			v6 = a.IP
+		}
+	}
+	if v6 != nil && f != FamilyIPv4 {
+		return v6, nil
+	}
+
+	if f == FamilyAny {
+		return nil, fmt.Errorf("no address for %s", host)
+	}
+	return nil, fmt.Errorf("no %s address for %s", f, host)
+}
+
+// familyDialer dials only within one family. Its Dial method satisfies the
+// dialer interfaces of libraries that only accept a network and address.
+type familyDialer struct {
+	net.Dialer
+	family Family
+}
+
+func (d *familyDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (d *familyDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d.Dialer.DialContext(ctx, d.family.Network(network), address)
+}
@@ -0,0 +1,53 @@
+package probe
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFamilyNetwork(t *testing.T) {
+	tests := []struct {
+		family  Family
+		network string
+		want    string
+	}{
+		{FamilyAny, "tcp", "tcp"},
+		{FamilyIPv4, "tcp", "tcp4"},
+		{FamilyIPv6, "udp", "udp6"},
+		{FamilyIPv6, "ip", "ip6"},
+		{FamilyIPv6, "tcp4", "tcp4"},
+	}
+	for _, tt := range tests {
+		if got := tt.family.Network(tt.network); got != tt.want {
+			t.Errorf("%v.Network(%q) = %q, want %q", tt.family, tt.network, got, tt.want)
+		}
+	}
+}
+
+func TestResolveIP(t *testing.T) {
+	ctx := context.Background()
+
+	if ip, err := ResolveIP(ctx, "127.0.0.1", FamilyAny); err != nil || ip.String() != "127.0.0.1" {
+		t.Errorf("ResolveIP(127.0.0.1, any) = %v, %v", ip, err)
+	}
+	if ip, err := ResolveIP(ctx, "::1", FamilyIPv6); err != nil || ip.String() != "::1" {
+		t.Errorf("ResolveIP(::1, ipv6) = %v, %v", ip, err)
+	}
+	if _, err := ResolveIP(ctx, "127.0.0.1", FamilyIPv6); err == nil {
+		t.Error("ResolveIP(127.0.0.1, ipv6): want error")
+	}
+	if _, err := ResolveIP(ctx, "::1", FamilyIPv4); err == nil {
+		t.Error("ResolveIP(::1, ipv4): want error")
+	}
+}
+
+func TestConnectScannerWrongFamily(t *testing.T) {
+	s := &ConnectScanner{Host: "127.0.0.1", Ports: NewPortSet(80), Concurrency: 1, Family: FamilyIPv6}
+	res, err := s.Probe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Success || res.ScanData != nil {
+		t.Errorf("scan of an IPv4 literal with FamilyIPv6 = %+v, want a resolution failure", res)
+	}
+}
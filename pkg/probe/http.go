@@ -13,6 +13,8 @@ type HTTPProber struct {
 	Method        string
 	Timeout       time.Duration
 	SkipTLSVerify bool
+	// Family restricts connections to IPv4 or IPv6.
+	Family Family
 }
 
 func (h *HTTPProber) Type() string {
@@ -40,10 +42,15 @@ func (h *HTTPProber) Probe(ctx context.Context) (Result, error) {
 		},
 	}
 
-	if h.SkipTLSVerify {
-		client.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	if h.SkipTLSVerify || h.Family != FamilyAny {
+		transport := &http.Transport{}
+		if h.SkipTLSVerify {
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 		}
+		if h.Family != FamilyAny {
+			transport.DialContext = (&familyDialer{family: h.Family}).DialContext
+		}
+		client.Transport = transport
 	}
 
 	resp, err := client.Do(req)
@@ -0,0 +1,193 @@
+package probe
+
+import (
+	"context"
+	"math"
+	"slices"
+	"time"
+)
+
+// MTRProber traces a path continuously, like mtr. Each cycle sends one
+// probe to every hop up to the destination over the same socket, and the
+// replies accumulate into per-hop loss and RTT statistics, which show where
+// along the path loss begins.
+type MTRProber struct {
+	Host    string
+	MaxHops int
+	// Timeout is how long each probe waits for its reply.
+	Timeout time.Duration
+	Family  Family
+	// Method and Port select the probe type, as for TraceProber.
+	Method string
+	Port   int
+	// Parallel is the number of hops probed at once, as for TraceProber.
+	Parallel int
+	// Cycles is the number of cycles to run; zero runs until ctx is
+	// cancelled.
+	Cycles int
+	// Interval is the pause between cycles.
+	Interval time.Duration
+	// Progress, if set, is called with a snapshot of the statistics after
+	// every cycle.
+	Progress func(MTRData)
+}
+
+// mtrRediscoverCycles is how often MTR probes past the destination again,
+// in case the path to it has grown longer.
+const mtrRediscoverCycles = 10
+
+func (m *MTRProber) Type() string {
+	return "mtr"
+}
+
+func (m *MTRProber) Probe(ctx context.Context) (Result, error) {
+	start := time.Now()
+
+	tracer := &TraceProber{
+		Host:    m.Host,
+		MaxHops: m.MaxHops,
+		Timeout: m.Timeout,
+		Family:  m.Family,
+		Method:  m.Method,
+		Port:    m.Port,
+	}
+	session, failed := tracer.openTrace("mtr")
+	if session == nil {
+		return failed, nil
+	}
+	defer session.Close()
+
+	data := &MTRData{Destination: session.dest.String(), Method: session.method, Port: session.dstPort}
+	names := make(map[string]string)
+	limit := m.MaxHops
+
+	for cycle := 0; m.Cycles == 0 || cycle < m.Cycles; cycle++ {
+		if cycle > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(m.Interval):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		path := session.probePath(ctx, limit, 1, m.Parallel)
+		if ctx.Err() != nil {
+			// Leave the interrupted cycle out of the statistics.
+			break
+		}
+
+		var unnamed []string
+		for _, probes := range path {
+			if p := probes[0]; !p.Timeout && !slices.Contains(unnamed, p.IP) {
+				if _, ok := names[p.IP]; !ok {
+					unnamed = append(unnamed, p.IP)
+				}
+			}
+		}
+		found := lookupNames(ctx, unnamed)
+		for _, ip := range unnamed {
+			names[ip] = found[ip]
+		}
+
+		for i, probes := range path {
+			p := probes[0]
+			if !p.Timeout {
+				p.HostName = names[p.IP]
+			}
+			data.record(i+1, p)
+		}
+
+		// Hops past the destination never answer, so cycles stop at it.
+		// A router answering in its place means the route grew longer;
+		// since it can also grow behind silent hops, every
+		// mtrRediscoverCycles cycles go out to MaxHops regardless.
+		if n := len(path); n > 0 {
+			switch last := path[n-1][0]; {
+			case last.reached(session.tp):
+				data.Hops = data.Hops[:n]
+				limit = n
+			case !last.Timeout:
+				limit = m.MaxHops
+			}
+		}
+		if (cycle+1)%mtrRediscoverCycles == 0 {
+			limit = m.MaxHops
+		}
+
+		data.Cycles++
+		if m.Progress != nil {
+			m.Progress(data.snapshot())
+		}
+	}
+
+	severity := SeverityOK
+	message := "MTR complete"
+	if len(data.Hops) > 0 && data.Hops[len(data.Hops)-1].Loss > 0 {
+		severity = SeverityWarning
+		message = "Loss at the final hop"
+	}
+
+	return Result{
+		TimeStamp: time.Now(),
+		ProbeType: "mtr",
+		Target:    m.Host,
+		MTRData:   data,
+		Message:   message,
+		Severity:  severity,
+		Success:   true,
+		Latency:   time.Since(start),
+	}, nil
+}
+
+// record adds the outcome of one probe to hop ttl, growing the hop list as
+// the cycle goes deeper.
+func (d *MTRData) record(ttl int, p TraceProbe) {
+	for len(d.Hops) < ttl {
+		d.Hops = append(d.Hops, MTRHop{HopNumber: len(d.Hops) + 1})
+	}
+	d.Hops[ttl-1].record(p)
+}
+
+// snapshot returns a copy of d that later cycles do not modify.
+func (d *MTRData) snapshot() MTRData {
+	c := *d
+	c.Hops = slices.Clone(d.Hops)
+	for i := range c.Hops {
+		c.Hops[i].IPs = slices.Clone(c.Hops[i].IPs)
+	}
+	return c
+}
+
+// record adds one probe to the hop's statistics.
+func (h *MTRHop) record(p TraceProbe) {
+	h.Sent++
+	if !p.Timeout {
+		if !slices.Contains(h.IPs, p.IP) {
+			h.IPs = append(h.IPs, p.IP)
+		}
+		if h.HostName == "" {
+			h.HostName = p.HostName
+		}
+
+		h.Received++
+		h.Last = p.RTT
+		if h.Received == 1 || p.RTT < h.Best {
+			h.Best = p.RTT
+		}
+		if p.RTT > h.Worst {
+			h.Worst = p.RTT
+		}
+
+		// Welford's method keeps the mean and variance without storing
+		// every RTT of a long run.
+		rtt := float64(p.RTT)
+		delta := rtt - h.mean
+		h.mean += delta / float64(h.Received)
+		h.m2 += delta * (rtt - h.mean)
+		h.Avg = time.Duration(h.mean)
+		h.StdDev = time.Duration(math.Sqrt(h.m2 / float64(h.Received)))
+	}
+	h.Loss = float64(h.Sent-h.Received) / float64(h.Sent) * 100
+}
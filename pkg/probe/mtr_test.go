@@ -0,0 +1,51 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMTRHopRecord(t *testing.T) {
+	var hop MTRHop
+	for _, p := range []TraceProbe{
+		{IP: "10.0.0.1", HostName: "a.example.", RTT: 2 * time.Millisecond},
+		{Timeout: true},
+		{IP: "10.0.0.2", RTT: 4 * time.Millisecond},
+		{IP: "10.0.0.1", RTT: 9 * time.Millisecond},
+		{IP: "10.0.0.1", RTT: 5 * time.Millisecond},
+	} {
+		hop.record(p)
+	}
+
+	if hop.Sent != 5 || hop.Received != 4 || hop.Loss != 20 {
+		t.Errorf("sent/received/loss = %d/%d/%v, want 5/4/20", hop.Sent, hop.Received, hop.Loss)
+	}
+	if hop.Last != 5*time.Millisecond || hop.Best != 2*time.Millisecond || hop.Worst != 9*time.Millisecond {
+		t.Errorf("last/best/worst = %v/%v/%v", hop.Last, hop.Best, hop.Worst)
+	}
+	// RTTs 2, 4, 9, 5: mean 5, population variance (9+1+16+0)/4 = 6.5.
+	if hop.Avg != 5*time.Millisecond {
+		t.Errorf("Avg = %v, want 5ms", hop.Avg)
+	}
+	if want := 2549509 * time.Nanosecond; hop.StdDev < want-time.Microsecond || hop.StdDev > want+time.Microsecond {
+		t.Errorf("StdDev = %v, want about %v", hop.StdDev, want)
+	}
+	if !reflect.DeepEqual(hop.IPs, []string{"10.0.0.1", "10.0.0.2"}) || hop.HostName != "a.example." {
+		t.Errorf("IPs = %v, HostName = %q", hop.IPs, hop.HostName)
+	}
+}
+
+func TestMTRDataRecord(t *testing.T) {
+	var d MTRData
+	d.record(3, TraceProbe{IP: "10.0.0.3", RTT: time.Millisecond})
+	if len(d.Hops) != 3 || d.Hops[0].HopNumber != 1 || d.Hops[2].HopNumber != 3 || d.Hops[2].Received != 1 {
+		t.Fatalf("hops = %+v", d.Hops)
+	}
+
+	snap := d.snapshot()
+	d.record(3, TraceProbe{IP: "10.0.0.4", RTT: time.Millisecond})
+	if snap.Hops[2].Sent != 1 || len(snap.Hops[2].IPs) != 1 {
+		t.Errorf("snapshot changed by a later probe: %+v", snap.Hops[2])
+	}
+}
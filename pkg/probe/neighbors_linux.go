@@ -0,0 +1,55 @@
+//go:build linux
+
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// Neighbor table constants from linux/neighbour.h.
+const (
+	sizeofNdMsg = 12
+	ndaDst      = 1
+)
+
+// ipv6Neighbors dumps the kernel's IPv6 neighbor table over rtnetlink, as
+// `ip -6 neigh show dev ifi` does.
+func ipv6Neighbors(ifi *net.Interface) ([]neighbor, error) {
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETNEIGH, syscall.AF_INET6)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the neighbor table: %w", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the neighbor table: %w", err)
+	}
+
+	var neighbors []neighbor
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWNEIGH || len(m.Data) < sizeofNdMsg {
+			continue
+		}
+		// struct ndmsg: family, padding, ifindex, state, flags, type.
+		if m.Data[0] != syscall.AF_INET6 || int(int32(binary.NativeEndian.Uint32(m.Data[4:8]))) != ifi.Index {
+			continue
+		}
+		state := binary.NativeEndian.Uint16(m.Data[8:10])
+
+		for attrs := m.Data[sizeofNdMsg:]; len(attrs) >= syscall.SizeofRtAttr; {
+			n := int(binary.NativeEndian.Uint16(attrs[0:2]))
+			if n < syscall.SizeofRtAttr || n > len(attrs) {
+				break
+			}
+			if binary.NativeEndian.Uint16(attrs[2:4]) == ndaDst && n-syscall.SizeofRtAttr == net.IPv6len {
+				ip := make(net.IP, net.IPv6len)
+				copy(ip, attrs[syscall.SizeofRtAttr:n])
+				neighbors = append(neighbors, neighbor{IP: ip, State: state})
+			}
+			attrs = attrs[min((n+syscall.RTA_ALIGNTO-1)&^(syscall.RTA_ALIGNTO-1), len(attrs)):]
+		}
+	}
+	return neighbors, nil
+}
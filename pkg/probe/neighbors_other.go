@@ -0,0 +1,14 @@
+//go:build !linux
+
+package probe
+
+import (
+	"errors"
+	"net"
+)
+
+// ipv6Neighbors is only implemented for Linux; elsewhere discovery relies on
+// the ICMPv6 sweep alone.
+func ipv6Neighbors(_ *net.Interface) ([]neighbor, error) {
+	return nil, errors.New("reading the neighbor table is not supported on this platform")
+}
@@ -3,16 +3,26 @@ package probe
 import (
 	"context"
 	"fmt"
+	"math"
+	"net"
+	"strconv"
 	"time"
 
 	probing "github.com/prometheus-community/pro-bing"
+	"golang.org/x/net/icmp"
 )
 
+// PingProber pings a host. It picks its mode with DetectPingMode: ICMP over
+// a raw socket when privileged, ICMP over a datagram socket when the system
+// allows unprivileged ICMP, and otherwise a TCP connect "ping" to ports 443
+// and 80. The mode used is recorded in PingData.Mode.
 type PingProber struct {
 	Host     string
 	Count    int
 	Timeout  time.Duration
 	Interval time.Duration
+	// Family restricts the ping to ICMP over IPv4 or ICMPv6.
+	Family Family
 }
 
 func (p *PingProber) Type() string {
@@ -20,17 +30,7 @@ func (p *PingProber) Type() string {
 }
 
 func (p *PingProber) Probe(ctx context.Context) (Result, error) {
-	pinger, err := probing.NewPinger(p.Host)
-	if err != nil {
-		return Result{}, fmt.Errorf("failed to create pinger: %w", err)
-	}
-
-	pinger.Count = p.Count
-	pinger.Interval = p.Interval
-	pinger.Timeout = p.Timeout
-	pinger.SetPrivileged(true)
-
-	err = pinger.Resolve()
+	ip, err := ResolveIP(ctx, p.Host, p.Family)
 	if err != nil {
 		return Result{
 			Target:    p.Host,
@@ -42,23 +42,18 @@ func (p *PingProber) Probe(ctx context.Context) (Result, error) {
 		}, nil
 	}
 
-	err = pinger.RunWithContext(ctx)
-	if err != nil {
-		return Result{}, fmt.Errorf("ping failed: %w", err)
-	}
+	mode := DetectPingMode(ip)
 
-	stats := pinger.Statistics()
-
-	data := PingData{
-		ResolvedIP:  pinger.IPAddr().String(),
-		PacketsSent: stats.PacketsSent,
-		PacketsRecv: stats.PacketsRecv,
-		PacketLoss:  stats.PacketLoss,
-		MinRTT:      stats.MinRtt,
-		MaxRTT:      stats.MaxRtt,
-		AvgRTT:      stats.AvgRtt,
-		StdDevRTT:   stats.StdDevRtt,
+	var data PingData
+	if mode == PingModeTCP {
+		data = p.tcpPing(ctx, ip)
+	} else {
+		data, err = p.icmpPing(ctx, ip, mode)
+		if err != nil {
+			return Result{}, err
+		}
 	}
+	data.Mode = mode
 
 	// ── Severity logic ────────────────────────────────────────────────────────
 	// Now properly emits SeverityWarning for degraded (but not fully down) hosts.
@@ -70,20 +65,20 @@ func (p *PingProber) Probe(ctx context.Context) (Result, error) {
 	)
 
 	switch {
-	case stats.PacketLoss == 100:
+	case data.PacketLoss == 100:
 		// Total failure — host is unreachable
 		success = false
 		severity = SeverityError
 		message = "Host unreachable"
 
-	case stats.PacketLoss > 0 || stats.AvgRtt > 150*time.Millisecond:
+	case data.PacketLoss > 0 || data.AvgRTT > 150*time.Millisecond:
 		// Partial loss OR high latency — degraded but alive
 		success = true
 		severity = SeverityWarning
 		message = fmt.Sprintf(
 			"Degraded connectivity (loss: %.1f%%, avg: %s)",
-			stats.PacketLoss,
-			stats.AvgRtt.Round(time.Millisecond),
+			data.PacketLoss,
+			data.AvgRTT.Round(time.Millisecond),
 		)
 
 	default:
@@ -92,7 +87,7 @@ func (p *PingProber) Probe(ctx context.Context) (Result, error) {
 		severity = SeverityOK
 		message = fmt.Sprintf(
 			"Ping successful (avg: %s, loss: 0%%)",
-			stats.AvgRtt.Round(time.Millisecond),
+			data.AvgRTT.Round(time.Millisecond),
 		)
 	}
 	// ─────────────────────────────────────────────────────────────────────────
@@ -105,6 +100,171 @@ func (p *PingProber) Probe(ctx context.Context) (Result, error) {
 		Message:   message,
 		Severity:  severity,
 		Success:   success,
-		Latency:   stats.AvgRtt,
+		Latency:   data.AvgRTT,
+	}, nil
+}
+
+// icmpPing pings ip with ICMP echo requests, over a raw socket in
+// PingModePrivileged and a datagram socket otherwise.
+func (p *PingProber) icmpPing(ctx context.Context, ip net.IP, mode PingMode) (PingData, error) {
+	pinger := probing.New("")
+	pinger.SetIPAddr(&net.IPAddr{IP: ip})
+	pinger.Count = p.Count
+	pinger.Interval = p.Interval
+	pinger.Timeout = p.Timeout
+	pinger.SetPrivileged(mode == PingModePrivileged)
+
+	if err := pinger.RunWithContext(ctx); err != nil {
+		return PingData{}, fmt.Errorf("ping failed: %w", err)
+	}
+
+	stats := pinger.Statistics()
+	return PingData{
+		ResolvedIP:  ip.String(),
+		PacketsSent: stats.PacketsSent,
+		PacketsRecv: stats.PacketsRecv,
+		PacketLoss:  stats.PacketLoss,
+		MinRTT:      stats.MinRtt,
+		MaxRTT:      stats.MaxRtt,
+		AvgRTT:      stats.AvgRtt,
+		StdDevRTT:   stats.StdDevRtt,
 	}, nil
 }
+
+// tcpPing stands in for ICMP when no ICMP socket can be opened: each "echo"
+// is a TCP connect to the tcpPingPorts, timed until the first answer.
+func (p *PingProber) tcpPing(ctx context.Context, ip net.IP) PingData {
+	count := p.Count
+	if count <= 0 {
+		count = 1
+	}
+
+	var rtts []time.Duration
+	sent := 0
+	for i := 0; i < count && ctx.Err() == nil; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(p.Interval):
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		sent++
+		if rtt, ok := tcpPingOnce(ctx, ip, p.Timeout); ok {
+			rtts = append(rtts, rtt)
+		}
+	}
+
+	data := PingData{
+		ResolvedIP:  ip.String(),
+		PacketsSent: sent,
+		PacketsRecv: len(rtts),
+	}
+	if sent > 0 {
+		data.PacketLoss = float64(sent-len(rtts)) / float64(sent) * 100
+	}
+	data.MinRTT, data.AvgRTT, data.MaxRTT, data.StdDevRTT = rttStats(rtts)
+	return data
+}
+
+// PingMode is how a ping reaches its target.
+type PingMode string
+
+const (
+	// PingModePrivileged sends ICMP over a raw socket, which needs root or
+	// CAP_NET_RAW.
+	PingModePrivileged PingMode = "privileged"
+	// PingModeUnprivileged sends ICMP over a datagram socket, which Linux
+	// allows for groups in net.ipv4.ping_group_range (and macOS allows
+	// everyone).
+	PingModeUnprivileged PingMode = "unprivileged"
+	// PingModeTCP times TCP connects instead of ICMP echoes.
+	PingModeTCP PingMode = "tcp"
+	// PingModeNeighbors sends nothing: IPv6 discovery lists the kernel's
+	// neighbor table when no ICMPv6 socket is available.
+	PingModeNeighbors PingMode = "neighbors"
+)
+
+// DetectPingMode returns the best mode available for pinging ip: a raw ICMP
+// socket, then a datagram ICMP socket, then TCP connects.
+func DetectPingMode(ip net.IP) PingMode {
+	raw, dgram := "ip4:icmp", "udp4"
+	addr := "0.0.0.0"
+	if ip.To4() == nil {
+		raw, dgram = "ip6:ipv6-icmp", "udp6"
+		addr = "::"
+	}
+
+	if conn, err := icmp.ListenPacket(raw, addr); err == nil {
+		_ = conn.Close()
+		return PingModePrivileged
+	}
+	if conn, err := icmp.ListenPacket(dgram, addr); err == nil {
+		_ = conn.Close()
+		return PingModeUnprivileged
+	}
+	return PingModeTCP
+}
+
+// tcpPingPorts are dialed together for a TCP ping. Either accepting or
+// refusing the connection shows the host is up.
+var tcpPingPorts = []int{443, 80}
+
+// tcpPingOnce connects to ip on the tcpPingPorts and returns how long the
+// first answer took.
+func tcpPingOnce(ctx context.Context, ip net.IP, timeout time.Duration) (time.Duration, bool) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	answered := make(chan bool, len(tcpPingPorts))
+	for _, port := range tcpPingPorts {
+		go func(port int) {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+			if conn != nil {
+				_ = conn.Close()
+			}
+			state := classifyDialError(err)
+			answered <- state == PortOpen || state == PortClosed
+		}(port)
+	}
+
+	for range tcpPingPorts {
+		if <-answered {
+			return time.Since(start), true
+		}
+	}
+	return 0, false
+}
+
+// rttStats returns the minimum, mean, maximum and population standard
+// deviation of rtts, or zeros when there are none.
+func rttStats(rtts []time.Duration) (min, avg, max, stddev time.Duration) {
+	if len(rtts) == 0 {
+		return 0, 0, 0, 0
+	}
+
+	min, max = rtts[0], rtts[0]
+	var sum time.Duration
+	for _, rtt := range rtts {
+		sum += rtt
+		if rtt < min {
+			min = rtt
+		}
+		if rtt > max {
+			max = rtt
+		}
+	}
+	avg = sum / time.Duration(len(rtts))
+
+	var variance float64
+	for _, rtt := range rtts {
+		diff := float64(rtt - avg)
+		variance += diff * diff
+	}
+	stddev = time.Duration(math.Sqrt(variance / float64(len(rtts))))
+	return min, avg, max, stddev
+}
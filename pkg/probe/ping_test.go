@@ -1,6 +1,8 @@
 package probe
 
 import (
+	"context"
+	"net"
 	"testing"
 	"time"
 )
@@ -36,3 +38,31 @@ func TestPingSeverity(t *testing.T) {
 		})
 	}
 }
+
+func TestRTTStats(t *testing.T) {
+	min, avg, max, stddev := rttStats([]time.Duration{
+		2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond,
+		5 * time.Millisecond, 5 * time.Millisecond, 7 * time.Millisecond, 9 * time.Millisecond,
+	})
+	if min != 2*time.Millisecond || avg != 5*time.Millisecond || max != 9*time.Millisecond || stddev != 2*time.Millisecond {
+		t.Errorf("rttStats() = %v, %v, %v, %v; want 2ms, 5ms, 9ms, 2ms", min, avg, max, stddev)
+	}
+
+	if min, avg, max, stddev := rttStats(nil); min+avg+max+stddev != 0 {
+		t.Errorf("rttStats(nil) = %v, %v, %v, %v; want zeros", min, avg, max, stddev)
+	}
+}
+
+func TestTCPPingLoopback(t *testing.T) {
+	// Loopback answers every port, with a RST if nothing listens.
+	rtt, ok := tcpPingOnce(context.Background(), net.IPv4(127, 0, 0, 1), time.Second)
+	if !ok || rtt <= 0 {
+		t.Fatalf("tcpPingOnce(127.0.0.1) = %v, %v; want an answer", rtt, ok)
+	}
+
+	p := &PingProber{Count: 2, Interval: 10 * time.Millisecond, Timeout: time.Second}
+	data := p.tcpPing(context.Background(), net.IPv4(127, 0, 0, 1))
+	if data.PacketsSent != 2 || data.PacketsRecv != 2 || data.PacketLoss != 0 {
+		t.Errorf("tcpPing() = %+v", data)
+	}
+}
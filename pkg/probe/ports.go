@@ -0,0 +1,329 @@
+package probe
+
+import (
+	_ "embed"
+	"fmt"
+	"iter"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PortRange is an inclusive range of ports.
+type PortRange struct {
+	Start int
+	End   int
+}
+
+// PortSet is a sorted list of non-overlapping port ranges. It lets scanners
+// walk 1-65535 without allocating a slice of every port.
+type PortSet []PortRange
+
+// NewPortSet builds a PortSet from individual ports.
+func NewPortSet(ports ...int) PortSet {
+	set := make(PortSet, 0, len(ports))
+	for _, p := range ports {
+		set = append(set, PortRange{Start: p, End: p})
+	}
+	return set.normalize()
+}
+
+// Len returns the number of ports in the set.
+func (s PortSet) Len() int {
+	n := 0
+	for _, r := range s {
+		n += r.End - r.Start + 1
+	}
+	return n
+}
+
+// All yields every port in ascending order.
+func (s PortSet) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for _, r := range s {
+			for p := r.Start; p <= r.End; p++ {
+				if !yield(p) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Slice returns every port in the set.
+func (s PortSet) Slice() []int {
+	ports := make([]int, 0, s.Len())
+	for p := range s.All() {
+		ports = append(ports, p)
+	}
+	return ports
+}
+
+// String formats the set as a port spec, e.g. "22,80,8000-8100".
+func (s PortSet) String() string {
+	parts := make([]string, 0, len(s))
+	for _, r := range s {
+		if r.Start == r.End {
+			parts = append(parts, strconv.Itoa(r.Start))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", r.Start, r.End))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// normalize sorts the ranges and merges overlapping or adjacent ones.
+func (s PortSet) normalize() PortSet {
+	if len(s) == 0 {
+		return s
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i].Start < s[j].Start })
+
+	out := PortSet{s[0]}
+	for _, r := range s[1:] {
+		last := &out[len(out)-1]
+		if r.Start <= last.End+1 {
+			last.End = max(last.End, r.End)
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Subtract returns the ports in s that are not in t.
+func (s PortSet) Subtract(t PortSet) PortSet {
+	var out PortSet
+	for _, r := range s {
+		cur := []PortRange{r}
+		for _, x := range t {
+			var next []PortRange
+			for _, c := range cur {
+				if x.End < c.Start || x.Start > c.End {
+					next = append(next, c)
+					continue
+				}
+				if x.Start > c.Start {
+					next = append(next, PortRange{Start: c.Start, End: x.Start - 1})
+				}
+				if x.End < c.End {
+					next = append(next, PortRange{Start: x.End + 1, End: c.End})
+				}
+			}
+			cur = next
+		}
+		out = append(out, cur...)
+	}
+	return out.normalize()
+}
+
+// Named port sets accepted by ParsePortSet in addition to "topN".
+var namedPortSets = map[string][]int{
+	"web":  {80, 81, 443, 591, 3000, 5000, 8000, 8008, 8080, 8081, 8088, 8443, 8888, 9000, 9090, 9443},
+	"db":   {1433, 1434, 1521, 3306, 5432, 5984, 6379, 7474, 8086, 9042, 9200, 11211, 27017, 50000},
+	"mail": {25, 110, 143, 465, 587, 993, 995, 2525},
+}
+
+// ParsePortSet parses a port spec into a PortSet without expanding ranges.
+// The spec is a comma-separated list of:
+//
+//   - ports and ranges: "22", "8000-8100"
+//   - named sets: "web", "db", "mail", "all" and "topN" (e.g. "top100",
+//     "top1000") for the N most common TCP ports; N is at most
+//     MaxTopPorts()
+//   - service names from the embedded services table: "ssh", "https"
+//   - exclusions of any of the above, prefixed with "!": "1-1024,!25"
+//
+// Exclusions apply after every inclusion, regardless of order. An unknown
+// or out-of-range token is an error naming that token.
+func ParsePortSet(portStr string) (PortSet, error) {
+	var include, exclude PortSet
+
+	for _, part := range strings.Split(portStr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		negate := strings.HasPrefix(part, "!")
+		token := strings.TrimSpace(strings.TrimPrefix(part, "!"))
+
+		set, err := parsePortToken(token)
+		if err != nil {
+			return nil, err
+		}
+
+		if negate {
+			exclude = append(exclude, set...)
+		} else {
+			include = append(include, set...)
+		}
+	}
+
+	return include.normalize().Subtract(exclude.normalize()), nil
+}
+
+// ParsePortRange expands a port spec (see ParsePortSet) into a sorted slice
+// of ports.
+func ParsePortRange(portStr string) ([]int, error) {
+	set, err := ParsePortSet(portStr)
+	if err != nil {
+		return nil, err
+	}
+	return set.Slice(), nil
+}
+
+// parsePortToken parses a single spec token: a port, a range (reversed
+// bounds are swapped), a named set or a service name.
+func parsePortToken(token string) (PortSet, error) {
+	lower := strings.ToLower(token)
+
+	if lower == "all" {
+		return PortSet{{Start: 1, End: 65535}}, nil
+	}
+	if ports, ok := namedPortSets[lower]; ok {
+		return NewPortSet(ports...), nil
+	}
+	if n, ok := strings.CutPrefix(lower, "top"); ok {
+		if count, err := strconv.Atoi(n); err == nil {
+			if count < 1 || count > MaxTopPorts() {
+				return nil, fmt.Errorf("invalid port set %q: count must be 1-%d, the number of ranked ports", token, MaxTopPorts())
+			}
+			return TopPorts(count), nil
+		}
+	}
+
+	if start, end, ok := strings.Cut(token, "-"); ok && isDigits(start) && isDigits(end) {
+		lo, err1 := strconv.Atoi(strings.TrimSpace(start))
+		hi, err2 := strconv.Atoi(strings.TrimSpace(end))
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid port range %q", token)
+		}
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if lo < 1 || hi > 65535 {
+			return nil, fmt.Errorf("port range %q out of bounds (1-65535)", token)
+		}
+		return PortSet{{Start: lo, End: hi}}, nil
+	}
+
+	if isDigits(token) {
+		num, err := strconv.Atoi(token)
+		if err != nil || num < 1 || num > 65535 {
+			return nil, fmt.Errorf("port %q out of bounds (1-65535)", token)
+		}
+		return PortSet{{Start: num, End: num}}, nil
+	}
+
+	if port, ok := LookupService(lower); ok {
+		return PortSet{{Start: port, End: port}}, nil
+	}
+
+	return nil, fmt.Errorf("invalid port %q: not a number, range, named set or known service", token)
+}
+
+func isDigits(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+//go:embed data/services.tsv
+var servicesTSV string
+
+// serviceEntry is one row of the embedded services table.
+type serviceEntry struct {
+	name  string
+	port  int
+	proto string
+	rank  int
+}
+
+// servicesTable parses the embedded table once.
+var servicesTable = sync.OnceValue(func() []serviceEntry {
+	var entries []serviceEntry
+	for _, line := range strings.Split(servicesTSV, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Split(line, "\t")
+		if len(f) != 4 {
+			continue
+		}
+		port, err1 := strconv.Atoi(f[1])
+		rank, err2 := strconv.Atoi(f[3])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		entries = append(entries, serviceEntry{name: f[0], port: port, proto: f[2], rank: rank})
+	}
+	return entries
+})
+
+// LookupService returns the port registered for a service name, preferring
+// TCP entries.
+func LookupService(name string) (int, bool) {
+	name = strings.ToLower(name)
+	udp := 0
+	for _, e := range servicesTable() {
+		if e.name != name || e.name == "-" {
+			continue
+		}
+		if e.proto == "tcp" {
+			return e.port, true
+		}
+		if udp == 0 {
+			udp = e.port
+		}
+	}
+	return udp, udp != 0
+}
+
+// ServiceName returns the registered name for port over proto ("tcp" or
+// "udp"), or "" if the table has none.
+func ServiceName(port int, proto string) string {
+	for _, e := range servicesTable() {
+		if e.port == port && e.proto == proto && e.name != "-" {
+			return e.name
+		}
+	}
+	return ""
+}
+
+// TopPorts returns the n most common TCP ports in the services table's
+// ranking. There are MaxTopPorts() of them; a larger n returns them all.
+func TopPorts(n int) PortSet {
+	ranked := rankedTCPPorts()
+	return NewPortSet(ranked[:min(max(n, 0), len(ranked))]...)
+}
+
+// MaxTopPorts returns the number of ranked TCP ports TopPorts draws from.
+func MaxTopPorts() int {
+	return len(rankedTCPPorts())
+}
+
+// rankedTCPPorts lists the ranked TCP ports, most common first.
+var rankedTCPPorts = sync.OnceValue(func() []int {
+	var ranked []serviceEntry
+	for _, e := range servicesTable() {
+		if e.proto == "tcp" && e.rank > 0 {
+			ranked = append(ranked, e)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].rank < ranked[j].rank })
+
+	ports := make([]int, len(ranked))
+	for i, e := range ranked {
+		ports[i] = e.port
+	}
+	return ports
+})
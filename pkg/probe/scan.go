@@ -2,20 +2,57 @@ package probe
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
-	"sort"
 	"strconv"
-	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
+// ScanProgress is passed to a scanner's Progress callback after each port.
+// Port and State describe the port that just finished.
+type ScanProgress struct {
+	Host    string
+	Port    int
+	State   PortState
+	Done    int
+	Total   int
+	Open    int
+	Elapsed time.Duration
+}
+
+// PortsPerSec returns the average scan rate so far.
+func (p ScanProgress) PortsPerSec() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Done) / p.Elapsed.Seconds()
+}
+
+// ConnectScanner completes a full TCP handshake with each port. A fixed pool
+// of Concurrency workers pulls ports from the set, so memory use does not
+// grow with the number of ports scanned.
 type ConnectScanner struct {
 	Host        string
-	Ports       []int
+	Ports       PortSet
 	Timeout     time.Duration
 	Concurrency int
+	// Sem, if set, is shared with other scanners and replaces the
+	// per-scan Concurrency limit.
+	Sem Semaphore
+	// Progress, if set, is called after every port from a single goroutine.
+	Progress func(ScanProgress)
+	// Adaptive derives the timeout from measured RTTs (starting at Timeout),
+	// retries timed-out ports once, and shrinks the number of ports in
+	// flight when timeouts spike.
+	Adaptive bool
+	// Limiter, if set, caps connection attempts per second. It may be
+	// shared across scanners.
+	Limiter *RateLimiter
+	// Family restricts a host name to its IPv4 or IPv6 addresses.
+	Family Family
 }
 
 func (c *ConnectScanner) Type() string {
@@ -25,61 +62,111 @@ func (c *ConnectScanner) Type() string {
 func (c *ConnectScanner) Probe(ctx context.Context) (Result, error) {
 	startTime := time.Now()
 
-	var openPorts []int
-	results := make(chan int)
+	addr, failed := resolveScan(ctx, c.Host, c.Family)
+	if addr == nil {
+		return failed, nil
+	}
+
+	type portResult struct {
+		port    int
+		state   PortState
+		timeout bool
+	}
+
+	var (
+		rtt    *RTTEstimator
+		window *congestionWindow
+	)
+	if c.Adaptive {
+		rtt = NewRTTEstimator(c.Timeout)
+		window = newCongestionWindow(max(c.Concurrency, 1))
+	}
+
+	jobs := make(chan int)
+	results := make(chan portResult)
 	var wg sync.WaitGroup
-	sem := make(chan struct{}, c.Concurrency)
 
-	for _, port := range c.Ports {
+	for range max(c.Concurrency, 1) {
 		wg.Add(1)
-
-		go func(port int) {
+		go func() {
 			defer wg.Done()
+			dialer := net.Dialer{Timeout: c.Timeout}
 
-			select {
-			case sem <- struct{}{}:
-			case <-ctx.Done():
-				return
-			}
-			defer func() { <-sem }()
+			for port := range jobs {
+				if c.Sem != nil {
+					if c.Sem.Acquire(ctx) != nil {
+						continue
+					}
+				}
 
-			dialer := net.Dialer{Timeout: c.Timeout}
-			address := net.JoinHostPort(c.Host, strconv.Itoa(port))
+				state, timedOut := c.probePort(ctx, &dialer, addr, port, rtt, window)
 
-			conn, err := dialer.DialContext(ctx, "tcp", address)
-			if err == nil {
-				_ = conn.Close()
-				results <- port
+				if c.Sem != nil {
+					c.Sem.Release()
+				}
+				if ctx.Err() != nil {
+					continue
+				}
+				results <- portResult{port: port, state: state, timeout: timedOut}
 			}
-		}(port)
+		}()
 	}
 
+	go func() {
+		defer close(jobs)
+		for port := range c.Ports.All() {
+			select {
+			case jobs <- port:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
 	go func() {
 		wg.Wait()
 		close(results)
 	}()
 
-	for port := range results {
-		openPorts = append(openPorts, port)
+	data := &ScanData{
+		Address:    addr.String(),
+		TotalPorts: c.Ports.Len(),
+		Protocol:   "tcp",
+		ScanMethod: "connect",
 	}
 
-	sort.Ints(openPorts)
+	done := 0
+	for r := range results {
+		data.add(r.port, r.state)
+		if r.timeout {
+			data.Timeouts++
+		}
+		done++
 
-	duration := time.Since(startTime)
-	ms := duration.Milliseconds()
-	if ms == 0 {
-		ms = 1
+		if c.Progress != nil {
+			c.Progress(ScanProgress{
+				Host:    c.Host,
+				Port:    r.port,
+				State:   r.state,
+				Done:    done,
+				Total:   data.TotalPorts,
+				Open:    len(data.OpenPorts),
+				Elapsed: time.Since(startTime),
+			})
+		}
 	}
-	rate := int64(len(c.Ports)) / ms
+	data.sortPorts()
+	data.Refused = len(data.ClosedPorts)
 
-	data := &ScanData{
-		OpenPorts:  openPorts,
-		TotalPorts: len(c.Ports),
-		ScanMethod: "connect",
-		ScanRateMs: rate,
+	duration := time.Since(startTime)
+	data.setRate(done, duration)
+	if rtt != nil {
+		data.RTT = rtt.SRTT()
+		data.AdaptiveTimeout = rtt.Timeout()
+		data.Concurrency = window.current()
 	}
 
-	message := fmt.Sprintf("Found %d open ports", len(openPorts))
+	message := fmt.Sprintf("Found %d open ports", len(data.OpenPorts))
 
 	return Result{
 		Target:    c.Host,
@@ -89,51 +176,109 @@ func (c *ConnectScanner) Probe(ctx context.Context) (Result, error) {
 		Severity:  SeverityOK,
 		Message:   message,
 		ScanData:  data,
+		Latency:   duration,
 	}, nil
 }
 
-// parsePortRange converts strings like "80,443,1000-1005" into a slice of integers
-func ParsePortRange(portStr string) []int {
-	var result []int
-	parts := strings.Split(portStr, ",")
+// probePort dials one port of addr. With an RTT estimator the timeout follows the
+// estimate, answers feed it, and a timed-out port is retried once with
+// double the timeout before being reported as filtered.
+func (c *ConnectScanner) probePort(
+	ctx context.Context,
+	dialer *net.Dialer,
+	addr net.IP,
+	port int,
+	rtt *RTTEstimator,
+	window *congestionWindow,
+) (PortState, bool) {
+	address := net.JoinHostPort(addr.String(), strconv.Itoa(port))
 
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
+	attempts := 1
+	if rtt != nil {
+		attempts = 2
+	}
 
-		if strings.Contains(part, "-") {
-			rangeParts := strings.Split(part, "-")
-			if len(rangeParts) != 2 {
-				continue
+	var (
+		state    PortState
+		timedOut bool
+	)
+	for attempt := range attempts {
+		if window != nil {
+			if window.acquire(ctx) != nil {
+				return PortFiltered, false
 			}
-
-			start, err1 := strconv.Atoi(strings.TrimSpace(rangeParts[0]))
-			end, err2 := strconv.Atoi(strings.TrimSpace(rangeParts[1]))
-			if err1 != nil || err2 != nil {
-				continue
+		}
+		if c.Limiter.Wait(ctx) != nil {
+			if window != nil {
+				window.release(false, 0)
 			}
+			return PortFiltered, false
+		}
 
-			if start > end {
-				start, end = end, start
-			}
+		if rtt != nil {
+			dialer.Timeout = min(rtt.Timeout()<<attempt, MaxScanTimeout)
+		}
 
-			if start < 1 || start > 65535 || end < 1 || end > 65535 {
-				continue
-			}
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, c.Family.Network("tcp"), address)
+		elapsed := time.Since(start)
+		if err == nil {
+			_ = conn.Close()
+		}
 
-			for i := start; i <= end; i++ {
-				result = append(result, i)
-			}
-		} else {
-			num, err := strconv.Atoi(part)
-			if err != nil {
-				continue
-			}
-			if num < 1 || num > 65535 {
-				continue
-			}
-			result = append(result, num)
+		state, timedOut = classifyDialError(err), isTimeout(err)
+		if rtt != nil && (state == PortOpen || state == PortClosed) {
+			rtt.Observe(elapsed)
+		}
+		if window != nil {
+			window.release(timedOut, dialer.Timeout)
+		}
+
+		if !timedOut {
+			break
+		}
+	}
+	return state, timedOut
+}
+
+// resolveScan resolves host once, within family f, so that every port is
+// probed at the same address and the result can record which one that was.
+// When host has no such address, the IP is nil and the Result reports why.
+func resolveScan(ctx context.Context, host string, f Family) (net.IP, Result) {
+	ip, err := ResolveIP(ctx, host, f)
+	if err != nil {
+		return nil, Result{
+			Target:    host,
+			TimeStamp: time.Now(),
+			ProbeType: "scan",
+			Success:   false,
+			Severity:  SeverityError,
+			Message:   fmt.Sprintf("DNS Resolution Failed: %v", err),
 		}
 	}
+	return ip, Result{}
+}
+
+// classifyDialError maps the outcome of a TCP dial to a PortState.
+func classifyDialError(err error) PortState {
+	if err == nil {
+		return PortOpen
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return PortClosed
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return PortUnreachable
+	default:
+		// Timeouts and anything unexpected are treated as filtered, the
+		// conservative reading for a firewall audit.
+		return PortFiltered
+	}
+}
 
-	return result
+// isTimeout reports whether err is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
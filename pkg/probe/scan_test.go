@@ -1,30 +1,41 @@
 package probe
 
 import (
+	"context"
+	"errors"
+	"net"
+	"os"
 	"reflect"
+	"slices"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestParsePortRange(t *testing.T) {
 	tests := []struct {
-		name  string
-		input string
-		want  []int
+		name    string
+		input   string
+		want    []int
+		wantErr bool
 	}{
-		{"Single port", "80", []int{80}},
-		{"Comma list", "80,443", []int{80, 443}},
-		{"Range", "80-82", []int{80, 81, 82}},
+		{"Single port", "80", []int{80}, false},
+		{"Comma list", "80,443", []int{80, 443}, false},
+		{"Range", "80-82", []int{80, 81, 82}, false},
 		// Your code successfully auto-swaps reversed ranges!
-		{"Reversed range", "82-80", []int{80, 81, 82}},
-		// Your code gracefully ignores invalid input, returning nil
-		{"Invalid input", "abc", nil},
-		{"Out of range", "70000", nil},
-		{"Mixed", "80,443,8080-8081", []int{80, 443, 8080, 8081}},
+		{"Reversed range", "82-80", []int{80, 81, 82}, false},
+		// Invalid tokens are reported instead of silently dropped.
+		{"Invalid input", "abc", nil, true},
+		{"Out of range", "70000", nil, true},
+		{"Mixed", "80,443,8080-8081", []int{80, 443, 8080, 8081}, false},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ParsePortRange(tt.input)
+			got, err := ParsePortRange(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePortRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
 
 			// Safely handle nil vs empty slice comparisons
 			if len(got) == 0 && len(tt.want) == 0 {
@@ -37,3 +48,116 @@ func TestParsePortRange(t *testing.T) {
 		})
 	}
 }
+
+func TestClassifyDialError(t *testing.T) {
+	opErr := func(errno syscall.Errno) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want PortState
+	}{
+		{"Connected", nil, PortOpen},
+		{"Refused", opErr(syscall.ECONNREFUSED), PortClosed},
+		{"Host unreachable", opErr(syscall.EHOSTUNREACH), PortUnreachable},
+		{"Network unreachable", opErr(syscall.ENETUNREACH), PortUnreachable},
+		{"Timeout", &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, PortFiltered},
+		{"Unknown", errors.New("boom"), PortFiltered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyDialError(tt.err); got != tt.want {
+				t.Errorf("classifyDialError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectScannerStates(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	open := ln.Addr().(*net.TCPAddr).Port
+	closed := closedPort(t)
+
+	s := &ConnectScanner{Host: "127.0.0.1", Ports: NewPortSet(open, closed), Timeout: time.Second, Concurrency: 2}
+	res, err := s.Probe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res.ScanData.OpenPorts, []int{open}) {
+		t.Errorf("OpenPorts = %v, want [%d]", res.ScanData.OpenPorts, open)
+	}
+	if !slices.Contains(res.ScanData.ClosedPorts, closed) {
+		t.Errorf("ClosedPorts = %v, want %d", res.ScanData.ClosedPorts, closed)
+	}
+}
+
+// closedPort returns a loopback port that had a listener a moment ago and
+// should now refuse connections.
+func closedPort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	_ = ln.Close()
+	return port
+}
+
+func TestConnectScannerProgressAndStats(t *testing.T) {
+	ports := PortSet{{Start: 1, End: 200}}
+
+	var calls, lastDone int
+	s := &ConnectScanner{
+		Host:        "127.0.0.1",
+		Ports:       ports,
+		Timeout:     time.Second,
+		Concurrency: 16,
+		Progress: func(p ScanProgress) {
+			calls++
+			if p.Done <= lastDone || p.Total != ports.Len() {
+				t.Errorf("unexpected progress %+v after done=%d", p, lastDone)
+			}
+			lastDone = p.Done
+		},
+	}
+
+	res, err := s.Probe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := res.ScanData
+	if calls != ports.Len() {
+		t.Errorf("Progress called %d times, want %d", calls, ports.Len())
+	}
+	if got := len(d.OpenPorts) + len(d.ClosedPorts) + len(d.FilteredPorts) + len(d.UnreachablePorts); got != ports.Len() {
+		t.Errorf("classified %d ports, want %d", got, ports.Len())
+	}
+	if d.Refused != len(d.ClosedPorts) {
+		t.Errorf("Refused = %d, want %d", d.Refused, len(d.ClosedPorts))
+	}
+	if d.PortsPerSec <= 0 || d.Elapsed <= 0 {
+		t.Errorf("PortsPerSec = %v, Elapsed = %v, want both > 0", d.PortsPerSec, d.Elapsed)
+	}
+}
+
+func BenchmarkConnectScannerLoopback(b *testing.B) {
+	ports := PortSet{{Start: 1, End: 65535}}
+	for b.Loop() {
+		s := &ConnectScanner{Host: "127.0.0.1", Ports: ports, Timeout: time.Second, Concurrency: 500}
+		res, err := s.Probe(context.Background())
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportMetric(res.ScanData.PortsPerSec, "ports/s")
+	}
+}
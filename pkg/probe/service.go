@@ -0,0 +1,266 @@
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServiceInfo describes what was found listening on an open port.
+type ServiceInfo struct {
+	Port       int    `json:"port"`
+	Service    string `json:"service"`
+	Version    string `json:"version,omitempty"`
+	TLS        bool   `json:"tls"`
+	TLSVersion string `json:"tls_version,omitempty"`
+	Banner     string `json:"banner,omitempty"`
+}
+
+// bannerMatcher recognises a service from the first bytes it sends. If the
+// pattern has a capture group, the first one becomes the version.
+type bannerMatcher struct {
+	service string
+	pattern *regexp.Regexp
+}
+
+var bannerMatchers = []bannerMatcher{
+	{"ssh", regexp.MustCompile(`^SSH-[\d.]+-(\S+)`)},
+	{"ftp", regexp.MustCompile(`(?i)^220[ -].*?((?:vsFTPd|ProFTPD|Pure-FTPd|FileZilla Server)(?: [\w.]+)?)`)},
+	{"ftp", regexp.MustCompile(`(?i)^220[ -].*\bFTP\b`)},
+	{"smtp", regexp.MustCompile(`(?i)^220[ -]\S+ E?SMTP ?([\w.-]+(?: [\d.]+)?)?`)},
+	{"pop3", regexp.MustCompile(`^\+OK`)},
+	{"imap", regexp.MustCompile(`^\* OK`)},
+	{"redis", regexp.MustCompile(`^(?:-ERR|-NOAUTH|-DENIED|\+PONG)`)},
+	{"mysql", regexp.MustCompile(`^.{4}\x0a([\w.-]+)\x00`)},
+}
+
+var redisVersion = regexp.MustCompile(`redis_version:([\w.]+)`)
+
+// DetectServices identifies the service behind each port on host, running
+// up to concurrency detections at once. Results are sorted by port.
+func DetectServices(ctx context.Context, host string, ports []int, timeout time.Duration, concurrency int) []ServiceInfo {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		services = make([]ServiceInfo, 0, len(ports))
+		sem      = make(chan struct{}, max(concurrency, 1))
+	)
+
+	for _, port := range ports {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			info := DetectService(ctx, host, port, timeout)
+			mu.Lock()
+			services = append(services, info)
+			mu.Unlock()
+		}(port)
+	}
+	wg.Wait()
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Port < services[j].Port })
+	return services
+}
+
+// DetectService connects to host:port and works out what is listening. It
+// first waits for an unsolicited banner (SSH, SMTP, FTP, ...), then attempts
+// a TLS handshake followed by HEAD over TLS, and finally nudges the port
+// with a plain HTTP HEAD. Ports that answer none of these are reported as
+// "unknown" with whatever bytes they sent as the banner.
+func DetectService(ctx context.Context, host string, port int, timeout time.Duration) ServiceInfo {
+	info := ServiceInfo{Port: port, Service: "unknown"}
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+
+	// 1. Passive banner.
+	banner, _ := exchange(ctx, address, timeout, nil)
+	if identify(&info, banner) {
+		return info
+	}
+
+	// 2. TLS, then HTTP inside it. This runs before the plain HTTP nudge
+	// because TLS servers answer plaintext with an HTTP 400 of their own.
+	if detectTLS(ctx, &info, host, address, timeout) {
+		return info
+	}
+
+	// 3. Plain HTTP. Redis rejects the HEAD with a RESP error, which is
+	// enough to recognise it.
+	reply, _ := exchange(ctx, address, timeout, httpHead(host))
+	if identify(&info, reply) {
+		if info.Service == "redis" {
+			info.Version = redisInfoVersion(ctx, address, timeout)
+		}
+		return info
+	}
+
+	if len(banner) == 0 {
+		banner = reply
+	}
+	info.Banner = cleanBanner(banner)
+	return info
+}
+
+// exchange dials address, optionally writes payload, and returns what the
+// server sent back before going quiet.
+func exchange(ctx context.Context, address string, timeout time.Duration, payload []byte) ([]byte, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if payload != nil {
+		_ = conn.SetWriteDeadline(time.Now().Add(timeout))
+		if _, err := conn.Write(payload); err != nil {
+			return nil, err
+		}
+	}
+	return readReply(conn, timeout), nil
+}
+
+// readReply waits up to timeout for the first bytes, then keeps reading
+// until the peer pauses briefly, closes, or 4 KiB have arrived.
+func readReply(conn net.Conn, timeout time.Duration) []byte {
+	var out []byte
+	buf := make([]byte, 1024)
+	deadline := time.Now().Add(timeout)
+
+	for len(out) < 4096 {
+		_ = conn.SetReadDeadline(deadline)
+		n, err := conn.Read(buf)
+		out = append(out, buf[:n]...)
+		if err != nil {
+			break
+		}
+		deadline = time.Now().Add(150 * time.Millisecond)
+	}
+	return out
+}
+
+// identify fills in info from a reply and reports whether it matched.
+func identify(info *ServiceInfo, reply []byte) bool {
+	if len(reply) == 0 {
+		return false
+	}
+
+	if bytes.HasPrefix(reply, []byte("HTTP/")) {
+		info.Service = "http"
+		if info.TLS {
+			info.Service = "https"
+		}
+		info.Version = httpServerHeader(reply)
+		info.Banner = cleanBanner(reply)
+		return true
+	}
+
+	for _, m := range bannerMatchers {
+		match := m.pattern.FindSubmatch(reply)
+		if match == nil {
+			continue
+		}
+		info.Service = m.service
+		if len(match) > 1 {
+			info.Version = strings.TrimSpace(string(match[1]))
+		}
+		info.Banner = cleanBanner(reply)
+		return true
+	}
+	return false
+}
+
+// detectTLS attempts a TLS handshake and, if it succeeds, identifies the
+// service running inside the tunnel.
+func detectTLS(ctx context.Context, info *ServiceInfo, host, address string, timeout time.Duration) bool {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config: &tls.Config{
+			InsecureSkipVerify: true, //nolint:gosec // fingerprinting, not trusting
+			ServerName:         host,
+		},
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := dialer.DialContext(tctx, "tcp", address)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = conn.Close() }()
+
+	tconn := conn.(*tls.Conn)
+	info.TLS = true
+	info.TLSVersion = tls.VersionName(tconn.ConnectionState().Version)
+
+	_ = conn.SetWriteDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(httpHead(host)); err == nil {
+		if identify(info, readReply(conn, timeout)) {
+			return true
+		}
+	}
+
+	info.Service = "ssl/unknown"
+	return true
+}
+
+// redisInfoVersion asks a Redis server for its version. Servers that require
+// AUTH refuse, in which case the version stays empty.
+func redisInfoVersion(ctx context.Context, address string, timeout time.Duration) string {
+	reply, err := exchange(ctx, address, timeout, []byte("INFO server\r\n"))
+	if err != nil {
+		return ""
+	}
+	if m := redisVersion.FindSubmatch(reply); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+func httpHead(host string) []byte {
+	return []byte(fmt.Sprintf("HEAD / HTTP/1.0\r\nHost: %s\r\n\r\n", host))
+}
+
+// httpServerHeader extracts the Server header from a raw HTTP response.
+func httpServerHeader(reply []byte) string {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(reply)), nil)
+	if err != nil {
+		return ""
+	}
+	_ = resp.Body.Close()
+	return resp.Header.Get("Server")
+}
+
+// cleanBanner returns the first line of b with non-printable bytes removed,
+// truncated to 80 characters.
+func cleanBanner(b []byte) string {
+	line, _, _ := bytes.Cut(b, []byte("\n"))
+	s := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, string(line))
+	if len(s) > 80 {
+		s = s[:80]
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,120 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer accepts connections on loopback, writes banner (if any) and
+// answers each request line with respond (if set).
+func fakeServer(t *testing.T, banner string, respond func(line string) string) int {
+	t.Helper()
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer func() { _ = conn.Close() }()
+				if banner != "" {
+					_, _ = conn.Write([]byte(banner))
+				}
+				if respond == nil {
+					_, _ = bufio.NewReader(conn).ReadString('\n')
+					return
+				}
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+				_, _ = conn.Write([]byte(respond(strings.TrimSpace(line))))
+			}(conn)
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func serverPort(t *testing.T, rawURL string) int {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return port
+}
+
+func TestDetectService(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Server", "nginx/1.25.3")
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewUnstartedServer(handler)
+	secure.Config.ErrorLog = log.New(io.Discard, "", 0) // the banner probe hangs up mid-handshake
+	secure.StartTLS()
+	defer secure.Close()
+
+	redis := func(line string) string {
+		if line == "INFO server" {
+			return "$40\r\n# Server\r\nredis_version:7.2.4\r\n\r\n"
+		}
+		return "-ERR unknown command 'HEAD'\r\n"
+	}
+
+	tests := []struct {
+		name        string
+		port        int
+		wantService string
+		wantVersion string
+		wantTLS     bool
+	}{
+		{"SSH", fakeServer(t, "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13\r\n", nil), "ssh", "OpenSSH_9.6p1", false},
+		{"SMTP", fakeServer(t, "220 mail.example.com ESMTP Postfix\r\n", nil), "smtp", "Postfix", false},
+		{"FTP", fakeServer(t, "220 (vsFTPd 3.0.5)\r\n", nil), "ftp", "vsFTPd 3.0.5", false},
+		{"Redis", fakeServer(t, "", redis), "redis", "7.2.4", false},
+		{"HTTP", serverPort(t, plain.URL), "http", "nginx/1.25.3", false},
+		{"HTTPS", serverPort(t, secure.URL), "https", "nginx/1.25.3", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := DetectService(context.Background(), "127.0.0.1", tt.port, 500*time.Millisecond)
+			if info.Service != tt.wantService || info.Version != tt.wantVersion || info.TLS != tt.wantTLS {
+				t.Errorf("DetectService() = %+v, want service=%q version=%q tls=%v",
+					info, tt.wantService, tt.wantVersion, tt.wantTLS)
+			}
+		})
+	}
+}
+
+func TestDetectServicesSorted(t *testing.T) {
+	a := fakeServer(t, "SSH-2.0-dropbear_2022.83\r\n", nil)
+	b := fakeServer(t, "+OK POP3 ready\r\n", nil)
+
+	got := DetectServices(context.Background(), "127.0.0.1", []int{b, a}, 500*time.Millisecond, 2)
+	if len(got) != 2 || got[0].Port > got[1].Port {
+		t.Fatalf("DetectServices() = %+v, want 2 results sorted by port", got)
+	}
+}
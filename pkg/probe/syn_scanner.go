@@ -0,0 +1,422 @@
+package probe
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrRawSocketUnavailable is returned when a raw socket cannot be opened,
+// usually because the process lacks root or CAP_NET_RAW.
+var ErrRawSocketUnavailable = errors.New("raw sockets unavailable (requires root or CAP_NET_RAW)")
+
+// openRawTCP opens a raw TCP socket bound to local, IPv4 or IPv6 to match. It is a variable so
+// tests can simulate missing privileges.
+var openRawTCP = listenRawTCP
+
+const (
+	tcpFlagFIN = 0x01
+	tcpFlagSYN = 0x02
+	tcpFlagRST = 0x04
+	tcpFlagACK = 0x10
+)
+
+// SynScanner performs a half-open TCP SYN scan over a raw socket. A SYN-ACK
+// marks a port open, a RST marks it closed and silence after all retries
+// marks it filtered.
+// The handshake is never completed. When raw sockets are unavailable it
+// falls back to a ConnectScanner and reports "connect" as the ScanMethod.
+type SynScanner struct {
+	Host    string
+	Ports   PortSet
+	Timeout time.Duration
+	// Concurrency is the number of SYNs sent per millisecond burst, and the
+	// worker count if the scan falls back to connect.
+	Concurrency int
+	// Retries is the number of extra SYNs sent to ports that did not answer.
+	Retries int
+	// Limiter, if set, paces SYNs instead of Concurrency bursts and may be
+	// shared across scanners.
+	Limiter *RateLimiter
+	// Sem, if set, is shared with other scanners and bounds in-flight
+	// ports across all of them. A SYN holds its slot until the port
+	// answers or Timeout passes.
+	Sem Semaphore
+	// Progress, if set, is called once for every port whose state is
+	// final: as soon as it answers, or after the last retry for ports that
+	// stay silent. Calls never overlap.
+	Progress func(ScanProgress)
+	// Family picks the IPv4 or IPv6 address of a host name. FamilyAny
+	// prefers IPv4.
+	Family Family
+}
+
+func (s *SynScanner) Type() string {
+	return "scan"
+}
+
+func (s *SynScanner) Probe(ctx context.Context) (Result, error) {
+	startTime := time.Now()
+
+	dst, failed := resolveScan(ctx, s.Host, s.Family)
+	if dst == nil {
+		return failed, nil
+	}
+
+	report := s.reporter(startTime)
+	states, complete, err := s.scan(ctx, dst, report)
+	if errors.Is(err, ErrRawSocketUnavailable) {
+		return s.fallback(ctx, err)
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("syn scan failed: %w", err)
+	}
+
+	data := &ScanData{
+		Address:    dst.String(),
+		TotalPorts: s.Ports.Len(),
+		Protocol:   "tcp",
+		ScanMethod: "syn",
+	}
+	done := 0
+	for port := range s.Ports.All() {
+		state, ok := states[port]
+		if !ok {
+			// A port is only known to be filtered once every retry went
+			// unanswered; an interrupted scan leaves it out.
+			if !complete {
+				continue
+			}
+			state = PortFiltered
+			data.Timeouts++
+			report(port, state)
+		}
+		data.add(port, state)
+		done++
+	}
+	data.sortPorts()
+	data.Refused = len(data.ClosedPorts)
+
+	duration := time.Since(startTime)
+	data.setRate(done, duration)
+
+	return Result{
+		Target:    s.Host,
+		TimeStamp: time.Now(),
+		ProbeType: "scan",
+		Success:   true,
+		Severity:  SeverityOK,
+		Message:   fmt.Sprintf("Found %d open ports", len(data.OpenPorts)),
+		ScanData:  data,
+		Latency:   duration,
+	}, nil
+}
+
+// fallback runs a connect scan with the same settings and notes why.
+func (s *SynScanner) fallback(ctx context.Context, cause error) (Result, error) {
+	connect := &ConnectScanner{
+		Host:        s.Host,
+		Ports:       s.Ports,
+		Timeout:     s.Timeout,
+		Concurrency: s.Concurrency,
+		Sem:         s.Sem,
+		Progress:    s.Progress,
+		Limiter:     s.Limiter,
+		Family:      s.Family,
+	}
+
+	result, err := connect.Probe(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.Message = fmt.Sprintf("SYN scan unavailable (%v), fell back to connect scan. %s", cause, result.Message)
+	return result, nil
+}
+
+// reporter returns the function that feeds Progress as ports finish. The
+// reader goroutine of scan reports answered ports, then Probe reports the
+// silent ones once the reader has stopped, so calls never overlap.
+func (s *SynScanner) reporter(start time.Time) func(port int, state PortState) {
+	total := s.Ports.Len()
+	done, open := 0, 0
+	return func(port int, state PortState) {
+		if s.Progress == nil {
+			return
+		}
+		done++
+		if state == PortOpen {
+			open++
+		}
+		s.Progress(ScanProgress{
+			Host:    s.Host,
+			Port:    port,
+			State:   state,
+			Done:    done,
+			Total:   total,
+			Open:    open,
+			Elapsed: time.Since(start),
+		})
+	}
+}
+
+// scan sends SYNs to every port and returns the state of each port that
+// answered, calling report for each as it does. Ports missing from the map
+// never answered; complete is false if ctx ended the scan before every
+// retry had run, so their silence proves nothing.
+func (s *SynScanner) scan(ctx context.Context, dst net.IP, report func(int, PortState)) (states map[int]PortState, complete bool, err error) {
+	src, err := localIPFor(dst)
+	if err != nil {
+		return nil, false, err
+	}
+
+	conn, err := openRawTCP(src)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	srcPort := 32768 + rand.IntN(28232)
+	seq := rand.Uint32()
+
+	var mu sync.Mutex
+	states = make(map[int]PortState, len(s.Ports))
+	// held maps each port holding a Sem slot to the attempt that took it,
+	// so a stale timer cannot free a retry's slot.
+	held := make(map[int]int)
+
+	// release frees the slot port took on attempt, if it still holds it.
+	// Callers hold mu.
+	release := func(port, attempt int) {
+		if a, ok := held[port]; ok && a == attempt {
+			delete(held, port)
+			s.Sem.Release()
+		}
+	}
+	defer func() {
+		mu.Lock()
+		for port, attempt := range held {
+			release(port, attempt)
+		}
+		mu.Unlock()
+	}()
+
+	done := make(chan struct{})
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		buf := make([]byte, 1500)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			_ = conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				continue
+			}
+
+			ipAddr, ok := addr.(*net.IPAddr)
+			if !ok || !ipAddr.IP.Equal(dst) {
+				continue
+			}
+
+			port, state, ok := parseSynReply(buf[:n], srcPort, seq)
+			if !ok {
+				continue
+			}
+
+			mu.Lock()
+			_, seen := states[port]
+			if !seen {
+				states[port] = state
+			}
+			if attempt, ok := held[port]; ok {
+				release(port, attempt)
+			}
+			mu.Unlock()
+
+			if !seen {
+				report(port, state)
+			}
+		}
+	}()
+	defer func() {
+		close(done)
+		<-readerDone
+	}()
+
+	burst := max(s.Concurrency, 1)
+	pending := s.Ports
+
+	for attempt := 0; attempt <= s.Retries && len(pending) > 0; attempt++ {
+		sent := 0
+		for port := range pending.All() {
+			if ctx.Err() != nil {
+				return states, false, nil
+			}
+
+			if s.Limiter != nil && s.Limiter.Wait(ctx) != nil {
+				return states, false, nil
+			}
+			if s.Sem != nil {
+				if s.Sem.Acquire(ctx) != nil {
+					return states, false, nil
+				}
+				mu.Lock()
+				held[port] = attempt
+				mu.Unlock()
+				time.AfterFunc(s.Timeout, func() {
+					mu.Lock()
+					release(port, attempt)
+					mu.Unlock()
+				})
+			}
+
+			pkt := buildSYN(src, dst, srcPort, port, seq)
+			if _, err := conn.WriteTo(pkt, &net.IPAddr{IP: dst}); err != nil {
+				return nil, false, fmt.Errorf("failed to send SYN: %w", err)
+			}
+
+			if sent++; s.Limiter == nil && sent%burst == 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		select {
+		case <-time.After(s.Timeout):
+		case <-ctx.Done():
+			return states, false, nil
+		}
+
+		// Only the ports still silent are retried.
+		mu.Lock()
+		var unanswered PortSet
+		for port := range pending.All() {
+			if _, ok := states[port]; !ok {
+				unanswered = append(unanswered, PortRange{Start: port, End: port})
+			}
+		}
+		mu.Unlock()
+		pending = unanswered.normalize()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	result := make(map[int]PortState, len(states))
+	for k, v := range states {
+		result[k] = v
+	}
+	return result, true, nil
+}
+
+// parseSynReply inspects a TCP segment and reports which of our probes it
+// answers: a SYN-ACK means open, a RST means closed.
+func parseSynReply(seg []byte, srcPort int, seq uint32) (port int, state PortState, ok bool) {
+	if len(seg) < 20 {
+		return 0, "", false
+	}
+
+	if int(binary.BigEndian.Uint16(seg[2:4])) != srcPort {
+		return 0, "", false
+	}
+
+	flags := seg[13]
+	ack := binary.BigEndian.Uint32(seg[8:12])
+	if flags&tcpFlagACK != 0 && ack != seq+1 {
+		return 0, "", false
+	}
+
+	port = int(binary.BigEndian.Uint16(seg[0:2]))
+	switch {
+	case flags&(tcpFlagSYN|tcpFlagACK) == tcpFlagSYN|tcpFlagACK:
+		return port, PortOpen, true
+	case flags&tcpFlagRST != 0:
+		return port, PortClosed, true
+	default:
+		return 0, "", false
+	}
+}
+
+// buildSYN returns a TCP SYN segment (with an MSS option) and a valid
+// checksum for the given endpoints.
+func buildSYN(src, dst net.IP, srcPort, dstPort int, seq uint32) []byte {
+	seg := make([]byte, 24)
+	binary.BigEndian.PutUint16(seg[0:2], uint16(srcPort))
+	binary.BigEndian.PutUint16(seg[2:4], uint16(dstPort))
+	binary.BigEndian.PutUint32(seg[4:8], seq)
+	binary.BigEndian.PutUint32(seg[8:12], 0)
+	seg[12] = 6 << 4 // data offset: 6 words
+	seg[13] = tcpFlagSYN
+	binary.BigEndian.PutUint16(seg[14:16], 1024) // window
+	// MSS option: kind 2, length 4, 1460.
+	seg[20], seg[21] = 2, 4
+	binary.BigEndian.PutUint16(seg[22:24], 1460)
+
+	binary.BigEndian.PutUint16(seg[16:18], tcpChecksum(src, dst, seg))
+	return seg
+}
+
+// tcpChecksum computes the TCP checksum over the IPv4 or IPv6 pseudo-header
+// and seg. Both pseudo-headers sum to the addresses plus the protocol and
+// segment length; only the address width differs.
+func tcpChecksum(src, dst net.IP, seg []byte) uint16 {
+	return transportChecksum(6, src, dst, seg)
+}
+
+// transportChecksum is tcpChecksum for any transport protocol proto, such
+// as UDP (17).
+func transportChecksum(proto int, src, dst net.IP, seg []byte) uint16 {
+	var sum uint32
+
+	add := func(b []byte) {
+		for i := 0; i+1 < len(b); i += 2 {
+			sum += uint32(b[i])<<8 | uint32(b[i+1])
+		}
+		if len(b)%2 == 1 {
+			sum += uint32(b[len(b)-1]) << 8
+		}
+	}
+
+	if src4, dst4 := src.To4(), dst.To4(); src4 != nil && dst4 != nil {
+		add(src4)
+		add(dst4)
+	} else {
+		add(src.To16())
+		add(dst.To16())
+	}
+	sum += uint32(proto)
+	sum += uint32(len(seg))
+	add(seg)
+
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// localIPFor returns the local address the kernel would use to reach dst.
+func localIPFor(dst net.IP) (net.IP, error) {
+	network := "udp6"
+	if dst.To4() != nil {
+		network = "udp4"
+	}
+	conn, err := net.Dial(network, net.JoinHostPort(dst.String(), "9"))
+	if err != nil {
+		return nil, fmt.Errorf("no route to %s: %w", dst, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	local := conn.LocalAddr().(*net.UDPAddr).IP
+	if ip4 := local.To4(); ip4 != nil {
+		return ip4, nil
+	}
+	return local, nil
+}
@@ -0,0 +1,22 @@
+//go:build linux
+
+package probe
+
+import (
+	"fmt"
+	"net"
+)
+
+// listenRawTCP opens a raw "ip4:tcp" or "ip6:tcp" socket. The kernel builds
+// the IP header and delivers a copy of every inbound TCP segment for local.
+func listenRawTCP(local net.IP) (net.PacketConn, error) {
+	network := "ip6:tcp"
+	if local.To4() != nil {
+		network = "ip4:tcp"
+	}
+	conn, err := net.ListenPacket(network, local.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRawSocketUnavailable, err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package probe
+
+import "net"
+
+// listenRawTCP is unsupported outside Linux: BSD-derived kernels do not
+// deliver inbound TCP segments to raw sockets, so SYN scans fall back to
+// connect scans.
+func listenRawTCP(_ net.IP) (net.PacketConn, error) {
+	return nil, ErrRawSocketUnavailable
+}
@@ -0,0 +1,245 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTCPChecksum(t *testing.T) {
+	src := net.IPv4(10, 0, 0, 1).To4()
+	dst := net.IPv4(10, 0, 0, 2).To4()
+	seg := buildSYN(src, dst, 40000, 80, 12345)
+
+	// Re-summing a segment that carries a valid checksum yields zero.
+	if sum := tcpChecksum(src, dst, seg); sum != 0 {
+		t.Errorf("checksum over valid segment = %#04x, want 0", sum)
+	}
+
+	src6, dst6 := net.ParseIP("fd00::1"), net.ParseIP("fd00::2")
+	seg6 := buildSYN(src6, dst6, 40000, 80, 12345)
+	if sum := tcpChecksum(src6, dst6, seg6); sum != 0 {
+		t.Errorf("IPv6 checksum over valid segment = %#04x, want 0", sum)
+	}
+}
+
+func TestParseSynReply(t *testing.T) {
+	reply := func(flags byte, ack uint32) []byte {
+		seg := make([]byte, 20)
+		seg[0], seg[1] = 0, 80 // src port 80
+		seg[2], seg[3] = 0x9c, 0x40
+		seg[8], seg[9], seg[10], seg[11] = byte(ack>>24), byte(ack>>16), byte(ack>>8), byte(ack)
+		seg[13] = flags
+		return seg
+	}
+
+	tests := []struct {
+		name   string
+		seg    []byte
+		want   PortState
+		wantOK bool
+	}{
+		{"SYN-ACK", reply(tcpFlagSYN|tcpFlagACK, 101), PortOpen, true},
+		{"RST-ACK", reply(tcpFlagRST|tcpFlagACK, 101), PortClosed, true},
+		{"Wrong ack", reply(tcpFlagSYN|tcpFlagACK, 7), "", false},
+		{"Plain ACK", reply(tcpFlagACK, 101), "", false},
+		{"Short", []byte{1, 2, 3}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, state, ok := parseSynReply(tt.seg, 40000, 100)
+			if ok != tt.wantOK || state != tt.want {
+				t.Fatalf("parseSynReply() = (%d, %q, %v), want %q ok=%v", port, state, ok, tt.want, tt.wantOK)
+			}
+			if ok && port != 80 {
+				t.Errorf("port = %d, want 80", port)
+			}
+		})
+	}
+}
+
+func TestSynScannerFallback(t *testing.T) {
+	orig := openRawTCP
+	openRawTCP = func(net.IP) (net.PacketConn, error) { return nil, ErrRawSocketUnavailable }
+	defer func() { openRawTCP = orig }()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	s := &SynScanner{Host: "127.0.0.1", Ports: NewPortSet(port), Timeout: time.Second, Concurrency: 1}
+	res, err := s.Probe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ScanData == nil || res.ScanData.ScanMethod != "connect" {
+		t.Fatalf("ScanData = %+v, want connect fallback", res.ScanData)
+	}
+	if !slices.Contains(res.ScanData.OpenPorts, port) {
+		t.Errorf("OpenPorts = %v, want %d", res.ScanData.OpenPorts, port)
+	}
+}
+
+func TestSynScannerLoopback(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	open := ln.Addr().(*net.TCPAddr).Port
+	closed := closedPort(t)
+
+	s := &SynScanner{Host: "127.0.0.1", Ports: NewPortSet(open, closed), Timeout: 500 * time.Millisecond, Concurrency: 10}
+	res, err := s.Probe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ScanData.ScanMethod != "syn" {
+		t.Skip("raw sockets unavailable; skipping SYN scan test")
+	}
+	if !slices.Contains(res.ScanData.OpenPorts, open) {
+		t.Errorf("OpenPorts = %v, want %d", res.ScanData.OpenPorts, open)
+	}
+	if !slices.Contains(res.ScanData.ClosedPorts, closed) {
+		t.Errorf("ClosedPorts = %v, want %d", res.ScanData.ClosedPorts, closed)
+	}
+}
+
+func TestSynScannerLoopbackIPv6(t *testing.T) {
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 loopback unavailable")
+	}
+	defer func() { _ = ln.Close() }()
+	open := ln.Addr().(*net.TCPAddr).Port
+
+	spare, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := spare.Addr().(*net.TCPAddr).Port
+	_ = spare.Close()
+
+	s := &SynScanner{Host: "::1", Ports: NewPortSet(open, closed), Timeout: 500 * time.Millisecond, Concurrency: 10}
+	res, err := s.Probe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ScanData.ScanMethod != "syn" {
+		t.Skip("raw sockets unavailable; skipping SYN scan test")
+	}
+	if !slices.Contains(res.ScanData.OpenPorts, open) {
+		t.Errorf("OpenPorts = %v, want %d", res.ScanData.OpenPorts, open)
+	}
+	if !slices.Contains(res.ScanData.ClosedPorts, closed) {
+		t.Errorf("ClosedPorts = %v, want %d", res.ScanData.ClosedPorts, closed)
+	}
+}
+
+func TestSynScannerSharedSem(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	open := ln.Addr().(*net.TCPAddr).Port
+
+	// One slot shared by two scanners with more ports than slots: each SYN
+	// must free its slot when answered for the scans to finish.
+	sem := NewSemaphore(1)
+	ports := NewPortSet(open, closedPort(t), closedPort(t))
+	var wg sync.WaitGroup
+	results := make([]Result, 2)
+	start := time.Now()
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := &SynScanner{Host: "127.0.0.1", Ports: ports, Timeout: 2 * time.Second, Concurrency: 10, Sem: sem}
+			results[i], _ = s.Probe(context.Background())
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	for _, res := range results {
+		if res.ScanData == nil {
+			t.Fatalf("scan failed: %s", res.Message)
+		}
+		if res.ScanData.ScanMethod != "syn" {
+			t.Skip("raw sockets unavailable; skipping SYN scan test")
+		}
+		if !slices.Contains(res.ScanData.OpenPorts, open) {
+			t.Errorf("OpenPorts = %v, want %d", res.ScanData.OpenPorts, open)
+		}
+	}
+	if len(sem) != 0 {
+		t.Errorf("%d slots still held after the scans", len(sem))
+	}
+	// Slots held until the timeout would serialize the SYNs 2s apart.
+	if elapsed > 4*time.Second {
+		t.Errorf("scans took %v; answered ports did not free their slots", elapsed)
+	}
+}
+
+func TestSynScannerProgress(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	open := ln.Addr().(*net.TCPAddr).Port
+	closed := closedPort(t)
+
+	var got []ScanProgress
+	s := &SynScanner{
+		Host:        "127.0.0.1",
+		Ports:       NewPortSet(open, closed),
+		Timeout:     500 * time.Millisecond,
+		Concurrency: 10,
+		Progress:    func(p ScanProgress) { got = append(got, p) },
+	}
+	res, err := s.Probe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ScanData.ScanMethod != "syn" {
+		t.Skip("raw sockets unavailable; skipping SYN scan test")
+	}
+
+	states := make(map[int]PortState)
+	for _, p := range got {
+		states[p.Port] = p.State
+	}
+	if len(got) != 2 || states[open] != PortOpen || states[closed] != PortClosed {
+		t.Errorf("Progress calls = %+v, want %d open and %d closed", got, open, closed)
+	}
+	if last := got[len(got)-1]; last.Done != 2 || last.Total != 2 || last.Open != 1 {
+		t.Errorf("last progress = %+v", last)
+	}
+}
+
+func TestSynScannerInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := &SynScanner{Host: "127.0.0.1", Ports: NewPortSet(closedPort(t)), Timeout: 500 * time.Millisecond, Concurrency: 10}
+	res, err := s.Probe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ScanData.ScanMethod != "syn" {
+		t.Skip("raw sockets unavailable; skipping SYN scan test")
+	}
+	// Nothing was sent, so nothing is known to be filtered.
+	if len(res.ScanData.FilteredPorts) != 0 || res.ScanData.Timeouts != 0 {
+		t.Errorf("interrupted scan reported filtered ports %v", res.ScanData.FilteredPorts)
+	}
+}
@@ -0,0 +1,145 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxExpandedTargets bounds how many hosts a set of target specs may expand
+// to, so a mistyped /8 does not queue sixteen million scans.
+const MaxExpandedTargets = 1 << 16
+
+// ExpandTargets turns target specs into a de-duplicated list of hosts. Each
+// spec may be a hostname, an IP, a CIDR prefix or a comma-separated list of
+// those. IPv4 prefixes shorter than /31 omit the network and broadcast
+// addresses.
+func ExpandTargets(specs []string) ([]string, error) {
+	var hosts []string
+	seen := make(map[string]bool)
+
+	add := func(h string) error {
+		if seen[h] {
+			return nil
+		}
+		if len(hosts) >= MaxExpandedTargets {
+			return fmt.Errorf("targets expand to more than %d hosts", MaxExpandedTargets)
+		}
+		seen[h] = true
+		hosts = append(hosts, h)
+		return nil
+	}
+
+	for _, spec := range specs {
+		for _, part := range strings.Split(spec, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+
+			if !strings.Contains(part, "/") {
+				if err := add(part); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			prefix, err := netip.ParsePrefix(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %w", part, err)
+			}
+			prefix = prefix.Masked()
+
+			hostBits := prefix.Addr().BitLen() - prefix.Bits()
+			if hostBits > 16 {
+				return nil, fmt.Errorf("CIDR %q is too large (max /%d)", part, prefix.Addr().BitLen()-16)
+			}
+
+			skipEdges := prefix.Addr().Is4() && hostBits > 1
+			last := lastAddr(prefix)
+			for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+				if !(skipEdges && (addr == prefix.Addr() || addr == last)) {
+					if err := add(addr.String()); err != nil {
+						return nil, err
+					}
+				}
+				if addr == last {
+					break
+				}
+			}
+		}
+	}
+
+	return hosts, nil
+}
+
+// lastAddr returns the highest address in prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// Semaphore bounds concurrent work. Scanners that share one draw from a
+// single global budget instead of each applying their own Concurrency.
+type Semaphore chan struct{}
+
+// NewSemaphore returns a Semaphore admitting n concurrent holders.
+func NewSemaphore(n int) Semaphore {
+	return make(Semaphore, max(n, 1))
+}
+
+// Acquire blocks until a slot is free or ctx is done.
+func (s Semaphore) Acquire(ctx context.Context) error {
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (s Semaphore) Release() {
+	<-s
+}
+
+// livenessPorts are tried by HostAlive; between them they cover most
+// servers, routers and workstations.
+var livenessPorts = []int{80, 443, 22, 445}
+
+// HostAlive reports whether host answers on any common TCP port. A refused
+// connection counts: only a live host sends a RST. This works without
+// privileges, unlike an ICMP echo.
+func HostAlive(ctx context.Context, host string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	alive := make(chan bool, len(livenessPorts))
+	for _, port := range livenessPorts {
+		go func(port int) {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+			if err == nil {
+				_ = conn.Close()
+			}
+			alive <- err == nil || errors.Is(err, syscall.ECONNREFUSED)
+		}(port)
+	}
+
+	for range livenessPorts {
+		if <-alive {
+			return true
+		}
+	}
+	return false
+}
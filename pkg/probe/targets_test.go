@@ -0,0 +1,57 @@
+package probe
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpandTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []string
+		wantErr bool
+	}{
+		{"Single host", []string{"example.com"}, []string{"example.com"}, false},
+		{"Comma list", []string{"a.com, b.com,,c.com"}, []string{"a.com", "b.com", "c.com"}, false},
+		{"Deduplicated", []string{"10.0.0.1", "10.0.0.1,10.0.0.2"}, []string{"10.0.0.1", "10.0.0.2"}, false},
+		{"CIDR /30 skips network and broadcast", []string{"192.168.1.0/30"}, []string{"192.168.1.1", "192.168.1.2"}, false},
+		{"CIDR /31 keeps both", []string{"192.168.1.0/31"}, []string{"192.168.1.0", "192.168.1.1"}, false},
+		{"CIDR /32", []string{"192.168.1.7/32"}, []string{"192.168.1.7"}, false},
+		{"Unmasked CIDR", []string{"10.1.2.3/30"}, []string{"10.1.2.1", "10.1.2.2"}, false},
+		{"IPv6 /126", []string{"2001:db8::/126"}, []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"}, false},
+		{"Bad CIDR", []string{"10.0.0.0/33"}, nil, true},
+		{"Too large", []string{"10.0.0.0/8"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandTargets(tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandTargetsSlash16(t *testing.T) {
+	got, err := ExpandTargets([]string{"10.0.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 65534 {
+		t.Errorf("len = %d, want 65534", len(got))
+	}
+}
+
+func TestHostAliveLoopback(t *testing.T) {
+	// Loopback refuses on the liveness ports, which still proves it is up.
+	if !HostAlive(context.Background(), "127.0.0.1", time.Second) {
+		t.Error("HostAlive(127.0.0.1) = false, want true")
+	}
+}
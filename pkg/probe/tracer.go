@@ -1,19 +1,102 @@
 package probe
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
+	"math/rand/v2"
 	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/icmp"
 	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
+// TraceProber discovers the path to a host by sending probes with
+// increasing hop limits and collecting the Time Exceeded replies of the
+// routers along the way.
+//
+// Probes follow Paris traceroute: every probe of a trace has the same flow
+// identifiers (addresses, protocol, ports, and for ICMP the checksum), so
+// routers that balance flows over several paths send them all down the
+// same one. Probes are told apart by fields outside the flow hash instead:
+// the ICMP sequence, the UDP checksum or the TCP sequence number.
 type TraceProber struct {
 	Host    string
 	MaxHops int
+	// Queries is the number of probes sent per hop; zero sends one.
+	Queries int
+	// Timeout is how long each probe waits for its reply.
 	Timeout time.Duration
+	// Family restricts the trace to IPv4 (ICMP) or IPv6 (ICMPv6).
+	Family Family
+	// Method is the probe type: "icmp" echo requests (the default), "udp"
+	// datagrams or "tcp" SYNs. UDP and TCP probes get through firewalls
+	// that drop ICMP echo.
+	Method string
+	// Port is the destination port of UDP and TCP probes. Zero uses 33434
+	// for UDP and 443 for TCP.
+	Port int
+	// Parallel is the number of hops probed at once, a window that slides
+	// along the path as hops finish; zero probes every hop at once. One
+	// probes a hop at a time, waiting out each timeout before the next.
+	Parallel int
+}
+
+const (
+	traceUDPPort = 33434
+	traceTCPPort = 443
+
+	// traceLookups bounds the reverse DNS lookups run at once.
+	traceLookups = 16
+)
+
+// traceProto holds what differs between ICMP and ICMPv6 tracing.
+type traceProto struct {
+	network     string
+	listen      string
+	proto       int
+	echo        icmp.Type
+	echoReply   icmp.Type
+	timeExceed  icmp.Type
+	unreachable icmp.Type
+}
+
+var (
+	traceICMPv4 = traceProto{
+		"ip4:icmp", "0.0.0.0", 1,
+		ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply, ipv4.ICMPTypeTimeExceeded, ipv4.ICMPTypeDestinationUnreachable,
+	}
+	traceICMPv6 = traceProto{
+		"ip6:ipv6-icmp", "::", 58,
+		ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, ipv6.ICMPTypeTimeExceeded, ipv6.ICMPTypeDestinationUnreachable,
+	}
+)
+
+// A raw socket receives every packet of its protocol for the host,
+// including replies to other traces and pings. Probes therefore carry an ID
+// picked at random per process and a sequence number unique within it, and
+// a reply only counts when it (or, for errors, the packet it quotes) carries
+// both. UDP probes have no room for the ID; their fixed source port serves
+// instead.
+var (
+	traceEchoID = int(rand.Uint32N(0xffff)) + 1
+	traceSeq    atomic.Uint32
+)
+
+// nextTraceSeq returns a sequence number no other probe in this process is
+// using. It is never zero, which a UDP checksum cannot carry.
+func nextTraceSeq() int {
+	for {
+		if seq := int(traceSeq.Add(1) & 0xffff); seq != 0 {
+			return seq
+		}
+	}
 }
 
 func (t *TraceProber) Type() string {
@@ -24,138 +107,768 @@ func (t *TraceProber) Probe(ctx context.Context) (Result, error) {
 
 	startTime := time.Now()
 
-	// Graceful DNS failure
-	destAddr, err := net.ResolveIPAddr("ip4", t.Host)
-	if err != nil {
-		return Result{
+	session, failed := t.openTrace("trace")
+	if session == nil {
+		return failed, nil
+	}
+	defer session.Close()
+
+	// Cancellation returns the hops probed so far as a partial trace.
+	path := session.probePath(ctx, t.MaxHops, max(t.Queries, 1), t.Parallel)
+	resolveProbeNames(ctx, path)
+
+	hops := make([]TraceHop, 0, len(path))
+	for i, probes := range path {
+		hops = append(hops, newTraceHop(i+1, probes))
+	}
+
+	traceData := &TraceData{
+		Destination: session.dest.String(),
+		Method:      session.method,
+		Port:        session.dstPort,
+		Hops:        hops,
+	}
+
+	return Result{
+		TimeStamp: time.Now(),
+		ProbeType: "trace",
+		Target:    t.Host,
+		TraceData: traceData,
+		Message:   "Trace complete",
+		Severity:  SeverityOK,
+		Success:   true,
+		Latency:   time.Since(startTime),
+	}, nil
+}
+
+// traceSession is an open set of sockets tracing one destination. A trace
+// uses one session for all its hops, and MTR mode one for all its cycles.
+//
+// Probes go out on conn: the ICMP socket itself, or a raw UDP or TCP
+// socket. Reader goroutines turn every packet that answers one of the
+// session's probes into a traceReply and hand it to the probe waiting for
+// that sequence number in pending, so any number of probes can be in
+// flight at once.
+type traceSession struct {
+	icmpConn *icmp.PacketConn
+	conn     net.PacketConn
+	tp       traceProto
+	dest     *net.IPAddr
+	timeout  time.Duration
+
+	method  string
+	local   net.IP
+	srcPort int
+	dstPort int
+
+	// sendMu makes setting the hop limit and writing one probe atomic.
+	sendMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[int]chan traceReply
+	readers sync.WaitGroup
+}
+
+// traceReply is a packet answering the probe with sequence number seq.
+type traceReply struct {
+	seq   int
+	probe TraceProbe
+	at    time.Time
+}
+
+// openTrace resolves t.Host and opens the sockets to trace it. When either
+// fails, the session is nil and the returned Result reports why.
+func (t *TraceProber) openTrace(probeType string) (*traceSession, Result) {
+	failed := func(message string) (*traceSession, Result) {
+		return nil, Result{
 			Target:    t.Host,
 			TimeStamp: time.Now(),
-			ProbeType: "trace",
+			ProbeType: probeType,
 			Success:   false,
 			Severity:  SeverityError,
-			Message:   fmt.Sprintf("DNS Resolution Failed: %v", err),
-		}, nil
+			Message:   message,
+		}
 	}
 
-	conn, err := net.ListenPacket("ip4:1", "0.0.0.0")
+	method := t.Method
+	if method == "" {
+		method = "icmp"
+	}
+	if method != "icmp" && method != "udp" && method != "tcp" {
+		return failed(fmt.Sprintf("Unknown trace method %q (use icmp, udp or tcp)", method))
+	}
+	// The hop limit is a single byte, and the path is sized by it.
+	if t.MaxHops < 1 || t.MaxHops > 255 {
+		return failed(fmt.Sprintf("Invalid max hops %d (use 1-255)", t.MaxHops))
+	}
+
+	// Graceful DNS failure
+	destAddr, err := net.ResolveIPAddr(t.Family.Network("ip"), t.Host)
 	if err != nil {
-		return Result{
-			Target:    t.Host,
-			TimeStamp: time.Now(),
-			ProbeType: "trace",
-			Success:   false,
-			Severity:  SeverityError,
-			Message:   "Permission denied: Traceroute requires root/sudo privileges",
-		}, nil
+		return failed(fmt.Sprintf("DNS Resolution Failed: %v", err))
 	}
-	defer func() { _ = conn.Close() }()
 
-	p := ipv4.NewPacketConn(conn)
-	defer func() { _ = p.Close() }()
+	tp := traceICMPv4
+	if destAddr.IP.To4() == nil {
+		tp = traceICMPv6
+	}
 
-	icmpConn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	icmpConn, err := icmp.ListenPacket(tp.network, tp.listen)
 	if err != nil {
-		return Result{
-			Target:    t.Host,
-			TimeStamp: time.Now(),
-			ProbeType: "trace",
-			Success:   false,
-			Severity:  SeverityError,
-			Message:   "Permission denied: Traceroute requires root/sudo privileges",
-		}, nil
+		return failed("Permission denied: Traceroute requires root/sudo privileges")
 	}
-	defer func() { _ = icmpConn.Close() }()
 
-	var hops []TraceHop
+	// A raw ICMPv6 socket also sees neighbor discovery and router traffic;
+	// only the replies a trace can get are let through.
+	if tp.proto == 58 {
+		var filter ipv6.ICMPFilter
+		filter.SetAll(true)
+		filter.Accept(ipv6.ICMPTypeEchoReply)
+		filter.Accept(ipv6.ICMPTypeTimeExceeded)
+		filter.Accept(ipv6.ICMPTypeDestinationUnreachable)
+		_ = icmpConn.IPv6PacketConn().SetICMPFilter(&filter)
+	}
 
-	for ttl := 1; ttl <= t.MaxHops; ttl++ {
+	s := &traceSession{
+		icmpConn: icmpConn,
+		conn:     icmpConn,
+		tp:       tp,
+		dest:     destAddr,
+		timeout:  t.Timeout,
+		method:   method,
+		pending:  make(map[int]chan traceReply),
+	}
 
-		// Graceful context cancellation → return partial trace
-		if ctx.Err() != nil {
-			break
+	if method != "icmp" {
+		s.dstPort = t.Port
+		if s.dstPort == 0 {
+			s.dstPort = traceUDPPort
+			if method == "tcp" {
+				s.dstPort = traceTCPPort
+			}
 		}
+		s.srcPort = 32768 + rand.IntN(28232)
 
-		if err := p.SetTTL(ttl); err != nil {
-			break
+		s.local, err = localIPFor(destAddr.IP)
+		if err != nil {
+			_ = icmpConn.Close()
+			return failed(err.Error())
 		}
 
-		msg := icmp.Message{
-			Type: ipv4.ICMPTypeEcho,
-			Code: 0,
-			Body: &icmp.Echo{
-				ID:   1234,
-				Seq:  ttl,
-				Data: []byte("NETDIAG_TRACE"),
-			},
+		if method == "tcp" {
+			s.conn, err = openRawTCP(s.local)
+		} else {
+			s.conn, err = listenRawUDP(s.local)
 		}
-
-		msgBytes, err := msg.Marshal(nil)
 		if err != nil {
-			break
+			_ = icmpConn.Close()
+			return failed(fmt.Sprintf("Permission denied: %s traceroute requires root/sudo privileges", method))
 		}
+	}
+
+	s.readers.Add(1)
+	go s.readICMP()
+	if method == "tcp" {
+		s.readers.Add(1)
+		go s.readTCP()
+	}
 
-		startHop := time.Now()
+	return s, Result{}
+}
+
+// listenRawUDP opens a raw socket for sending hand-built UDP datagrams from
+// local, IPv4 or IPv6 to match.
+func listenRawUDP(local net.IP) (net.PacketConn, error) {
+	network := "ip6:udp"
+	if local.To4() != nil {
+		network = "ip4:udp"
+	}
+	return net.ListenPacket(network, local.String())
+}
 
-		if _, writeErr := p.WriteTo(msgBytes, nil, destAddr); writeErr != nil {
+// Close releases the session's sockets and stops its readers.
+func (s *traceSession) Close() {
+	_ = s.icmpConn.Close()
+	if s.conn != net.PacketConn(s.icmpConn) {
+		_ = s.conn.Close()
+	}
+	s.readers.Wait()
+}
+
+// probePath probes every hop up to maxHops with queries probes each,
+// keeping up to window hops (all of them if window is zero) in flight. It
+// returns the probes of each hop, from the first to the first that reached
+// the destination. Hops from the first one that could not be probed, e.g.
+// because ctx was cancelled, are left out.
+func (s *traceSession) probePath(ctx context.Context, maxHops, queries, window int) [][]TraceProbe {
+	if window <= 0 {
+		window = maxHops
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		hops    = make([][]TraceProbe, maxHops)
+		cancels = make([]context.CancelFunc, maxHops)
+		last    = maxHops // the lowest hop known to reach the destination
+		sem     = make(chan struct{}, max(window, 1))
+	)
+
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		mu.Lock()
+		if ctx.Err() != nil || ttl > last {
+			mu.Unlock()
 			break
 		}
+		hopCtx, cancel := context.WithCancel(ctx)
+		cancels[ttl-1] = cancel
+		mu.Unlock()
+
+		// Probes leave in TTL order, so the destination's ICMP rate limit
+		// spends itself on the lowest hops that reach it.
+		flights := s.sendHop(ttl, queries)
 
-		reply := make([]byte, 1500)
-		_ = icmpConn.SetReadDeadline(time.Now().Add(t.Timeout))
+		wg.Add(1)
+		go func(ttl int) {
+			defer wg.Done()
+			defer func() { <-sem }()
 
-		n, peer, err := icmpConn.ReadFrom(reply)
-		rtt := time.Since(startHop)
+			probes := s.awaitHop(hopCtx, flights)
 
-		hop := TraceHop{
-			HopNumber: ttl,
-			RTT:       rtt,
+			mu.Lock()
+			defer mu.Unlock()
+			hops[ttl-1] = probes
+
+			// Hops past the destination only repeat its answer; stop
+			// waiting for them.
+			if ttl < last && (TraceHop{Probes: probes}).reached(s.tp) {
+				last = ttl
+				for _, cancel := range cancels[ttl:] {
+					if cancel != nil {
+						cancel()
+					}
+				}
+			}
+		}(ttl)
+	}
+	wg.Wait()
+
+	for _, cancel := range cancels {
+		if cancel != nil {
+			cancel()
 		}
+	}
+
+	path := hops[:last]
+	for i, probes := range path {
+		if probes == nil {
+			return path[:i]
+		}
+	}
+	return path
+}
+
+// traceFlight is a probe that was sent and awaits its reply.
+type traceFlight struct {
+	seq   int
+	start time.Time
+	reply chan traceReply
+}
+
+// sendHop sends queries probes with the hop limit set to ttl and returns
+// those that went out.
+func (s *traceSession) sendHop(ttl, queries int) []*traceFlight {
+	flights := make([]*traceFlight, 0, queries)
+	for range queries {
+		if f, err := s.sendProbe(ttl); err == nil {
+			flights = append(flights, f)
+		}
+	}
+	return flights
+}
+
+// awaitHop waits for the replies to a hop's probes, which time out
+// together, and returns them in the order sent. Probes still waiting when
+// ctx is cancelled are left out; it returns nil if none are left.
+func (s *traceSession) awaitHop(ctx context.Context, flights []*traceFlight) []TraceProbe {
+	var probes []TraceProbe
+	for _, f := range flights {
+		if p, err := s.awaitReply(ctx, f); err == nil {
+			probes = append(probes, p)
+		}
+	}
+	return probes
+}
+
+// sendProbe sends one probe with hop limit ttl and registers it to receive
+// the reply matching its sequence number.
+func (s *traceSession) sendProbe(ttl int) (*traceFlight, error) {
+	f := &traceFlight{seq: nextTraceSeq(), reply: make(chan traceReply, 1)}
+	s.mu.Lock()
+	s.pending[f.seq] = f.reply
+	s.mu.Unlock()
+
+	// The hop limit is a socket option, so other probes must not change it
+	// between setting it and writing.
+	s.sendMu.Lock()
+	err := s.setHopLimit(ttl)
+	if err == nil {
+		f.start = time.Now()
+		_, err = s.conn.WriteTo(s.buildProbe(f.seq), s.dest)
+	}
+	s.sendMu.Unlock()
+
+	if err != nil {
+		s.forget(f)
+		return nil, err
+	}
+	return f, nil
+}
+
+// awaitReply waits up to the timeout from when f was sent for its reply. It
+// returns an error if ctx is cancelled first.
+func (s *traceSession) awaitReply(ctx context.Context, f *traceFlight) (TraceProbe, error) {
+	defer s.forget(f)
+
+	timer := time.NewTimer(time.Until(f.start.Add(s.timeout)))
+	defer timer.Stop()
+
+	var r traceReply
+	select {
+	case r = <-f.reply:
+	case <-timer.C:
+		// Waiting for the hop's earlier probes may have used up this one's
+		// time; a reply that already arrived still counts if it arrived
+		// within the timeout.
+		select {
+		case r = <-f.reply:
+		default:
+			return TraceProbe{Timeout: true}, nil
+		}
+	case <-ctx.Done():
+		return TraceProbe{}, ctx.Err()
+	}
+
+	r.probe.RTT = r.at.Sub(f.start)
+	if r.probe.RTT > s.timeout {
+		return TraceProbe{Timeout: true}, nil
+	}
+	return r.probe, nil
+}
 
-		// Timeout case
+// forget stops delivering replies to f.
+func (s *traceSession) forget(f *traceFlight) {
+	s.mu.Lock()
+	delete(s.pending, f.seq)
+	s.mu.Unlock()
+}
+
+// buildProbe returns the packet for probe seq, without the IP header the
+// kernel adds.
+func (s *traceSession) buildProbe(seq int) []byte {
+	switch s.method {
+	case "udp":
+		return buildTraceUDP(s.local, s.dest.IP, s.srcPort, s.dstPort, uint16(seq))
+	case "tcp":
+		return buildSYN(s.local, s.dest.IP, s.srcPort, s.dstPort, traceTCPSeq(seq))
+	}
+
+	// Two payload bytes cancel the sequence number out of the checksum,
+	// keeping the first four bytes of every echo request, which ECMP
+	// routers may hash, the same.
+	data := make([]byte, 2, 2+len("NETDIAG_TRACE"))
+	binary.BigEndian.PutUint16(data, ^onesAdd(uint16(traceEchoID), uint16(seq)))
+	data = append(data, "NETDIAG_TRACE"...)
+
+	msg := icmp.Message{
+		Type: s.tp.echo,
+		Code: 0,
+		Body: &icmp.Echo{
+			ID:   traceEchoID,
+			Seq:  seq,
+			Data: data,
+		},
+	}
+	b, _ := msg.Marshal(nil)
+	return b
+}
+
+// setHopLimit sets the IPv4 TTL or IPv6 hop limit for outgoing probes.
+func (s *traceSession) setHopLimit(ttl int) error {
+	if s.conn == net.PacketConn(s.icmpConn) {
+		if s.tp.proto == 58 {
+			return s.icmpConn.IPv6PacketConn().SetHopLimit(ttl)
+		}
+		return s.icmpConn.IPv4PacketConn().SetTTL(ttl)
+	}
+	if s.tp.proto == 58 {
+		return ipv6.NewPacketConn(s.conn).SetHopLimit(ttl)
+	}
+	return ipv4.NewPacketConn(s.conn).SetTTL(ttl)
+}
+
+// deliver hands a reply to the probe waiting for it. Replies to probes that
+// already timed out, and duplicates, are dropped.
+func (s *traceSession) deliver(r traceReply) {
+	s.mu.Lock()
+	reply, ok := s.pending[r.seq]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case reply <- r:
+	default:
+	}
+}
+
+// readICMP delivers the ICMP replies to the session's probes: echo replies,
+// and Time Exceeded and Destination Unreachable errors quoting a probe.
+func (s *traceSession) readICMP() {
+	defer s.readers.Done()
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := s.icmpConn.ReadFrom(buf)
 		if err != nil {
-			hop.Timeout = true
-			hop.IP = "*"
-			hops = append(hops, hop)
+			return // closed
+		}
+		at := time.Now()
+
+		m, ok := matchTraceReply(s.tp, buf[:n], s.dest.IP)
+		if !ok {
+			continue
+		}
+		seq, ok := s.probeSeq(m)
+		if !ok {
 			continue
 		}
 
-		parsedMsg, err := icmp.ParseMessage(1, reply[:n])
+		s.deliver(traceReply{
+			seq:   seq,
+			probe: TraceProbe{IP: peerIP(peer).String(), ICMPType: m.icmpType, ICMPCode: m.code},
+			at:    at,
+		})
+	}
+}
+
+// readTCP delivers the destination's answers to TCP probes: a SYN-ACK from
+// an open port or a RST from a closed one.
+func (s *traceSession) readTCP() {
+	defer s.readers.Done()
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := s.conn.ReadFrom(buf)
 		if err != nil {
-			hop.Timeout = true
-			hop.IP = "*"
-			hops = append(hops, hop)
+			return // closed
+		}
+		at := time.Now()
+
+		if !peerIP(peer).Equal(s.dest.IP) {
+			continue
+		}
+		seq, state, ok := parseTraceTCPReply(buf[:n], s.srcPort, s.dstPort)
+		if !ok {
 			continue
 		}
 
-		ipAddr := peer.String()
-		hop.IP = ipAddr
+		s.deliver(traceReply{
+			seq:   seq,
+			probe: TraceProbe{IP: s.dest.IP.String(), PortState: state},
+			at:    at,
+		})
+	}
+}
 
-		names, err := net.LookupAddr(ipAddr)
-		if err == nil && len(names) > 0 {
-			hop.HostName = names[0]
+// probeSeq returns the sequence number of the session's probe that an ICMP
+// reply answers, if it answers one.
+func (s *traceSession) probeSeq(m traceMatch) (int, bool) {
+	h := m.header
+	switch s.method {
+	case "udp":
+		if m.proto != 17 || !s.ownPorts(h) {
+			return 0, false
+		}
+		return int(binary.BigEndian.Uint16(h[6:8])), true
+	case "tcp":
+		if m.proto != 6 || !s.ownPorts(h) {
+			return 0, false
 		}
+		return tcpTraceSeq(binary.BigEndian.Uint32(h[4:8]))
+	}
+
+	if m.proto != s.tp.proto || int(binary.BigEndian.Uint16(h[4:6])) != traceEchoID {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint16(h[6:8])), true
+}
 
-		hops = append(hops, hop)
+// ownPorts reports whether a quoted UDP or TCP header is from this session.
+func (s *traceSession) ownPorts(h []byte) bool {
+	return int(binary.BigEndian.Uint16(h[0:2])) == s.srcPort && int(binary.BigEndian.Uint16(h[2:4])) == s.dstPort
+}
 
-		// Stop early if destination reached
-		if parsedMsg.Type == ipv4.ICMPTypeEchoReply &&
-			ipAddr == destAddr.String() {
-			break
+// traceTCPSeq returns the TCP sequence number carrying probe seq: the
+// process's trace ID in the high half, seq in the low half.
+func traceTCPSeq(seq int) uint32 {
+	return uint32(traceEchoID)<<16 | uint32(seq)
+}
+
+// tcpTraceSeq recovers the probe sequence number from a TCP sequence
+// number, if traceTCPSeq made it.
+func tcpTraceSeq(v uint32) (int, bool) {
+	if int(v>>16) != traceEchoID {
+		return 0, false
+	}
+	return int(v & 0xffff), true
+}
+
+// parseTraceTCPReply reads the destination's answer to a TCP probe sent
+// from srcPort to dstPort: a SYN-ACK means the port is open, a RST that it
+// is closed. Either acknowledges the probe's sequence number plus one.
+func parseTraceTCPReply(seg []byte, srcPort, dstPort int) (seq int, state PortState, ok bool) {
+	if len(seg) < 20 {
+		return 0, "", false
+	}
+	if int(binary.BigEndian.Uint16(seg[0:2])) != dstPort || int(binary.BigEndian.Uint16(seg[2:4])) != srcPort {
+		return 0, "", false
+	}
+
+	flags := seg[13]
+	if flags&tcpFlagACK == 0 {
+		return 0, "", false
+	}
+	seq, ok = tcpTraceSeq(binary.BigEndian.Uint32(seg[8:12]) - 1)
+	if !ok {
+		return 0, "", false
+	}
+
+	switch {
+	case flags&tcpFlagSYN != 0:
+		return seq, PortOpen, true
+	case flags&tcpFlagRST != 0:
+		return seq, PortClosed, true
+	}
+	return 0, "", false
+}
+
+// buildTraceUDP returns a UDP datagram from srcPort to dstPort whose
+// checksum is sum. Two payload bytes absorb the difference, so every probe
+// keeps the same ports and length, the flow routers hash, while the
+// checksum, which they ignore, tells the probes apart in quoted headers.
+func buildTraceUDP(src, dst net.IP, srcPort, dstPort int, sum uint16) []byte {
+	seg := make([]byte, 8+2+len("NETDIAG_TRACE"))
+	binary.BigEndian.PutUint16(seg[0:2], uint16(srcPort))
+	binary.BigEndian.PutUint16(seg[2:4], uint16(dstPort))
+	binary.BigEndian.PutUint16(seg[4:6], uint16(len(seg)))
+	copy(seg[10:], "NETDIAG_TRACE")
+
+	// With the checksum field and filler zero, the checksum would be
+	// ^partial. Adding ^sum - partial to the data makes it sum.
+	partial := transportChecksum(17, src, dst, seg)
+	binary.BigEndian.PutUint16(seg[8:10], onesAdd(^sum, partial))
+	binary.BigEndian.PutUint16(seg[6:8], sum)
+	return seg
+}
+
+// onesAdd adds two 16-bit words in ones' complement arithmetic.
+func onesAdd(a, b uint16) uint16 {
+	sum := uint32(a) + uint32(b)
+	return uint16(sum&0xffff + sum>>16)
+}
+
+// newTraceHop summarizes the probes sent to one hop. The hop's IP, host
+// name and ICMP type and code are those of its first reply, and its RTT is
+// the average.
+func newTraceHop(ttl int, probes []TraceProbe) TraceHop {
+	hop := TraceHop{HopNumber: ttl, Probes: probes, IP: "*", Timeout: true}
+
+	var rtts []time.Duration
+	for _, p := range probes {
+		if p.Timeout {
+			continue
+		}
+		if hop.Timeout {
+			hop.Timeout = false
+			hop.IP, hop.HostName = p.IP, p.HostName
+			hop.ICMPType, hop.ICMPCode = p.ICMPType, p.ICMPCode
 		}
+		if !slices.Contains(hop.IPs, p.IP) {
+			hop.IPs = append(hop.IPs, p.IP)
+		}
+		rtts = append(rtts, p.RTT)
 	}
 
-	traceData := &TraceData{
-		Hops: hops,
+	hop.Loss = float64(len(probes)-len(rtts)) / float64(len(probes)) * 100
+	hop.MinRTT, hop.AvgRTT, hop.MaxRTT, _ = rttStats(rtts)
+	hop.RTT = hop.AvgRTT
+	return hop
+}
+
+// reached reports whether any probe to the hop got an answer from the
+// destination or a destination unreachable error; either ends the trace.
+func (h TraceHop) reached(tp traceProto) bool {
+	return slices.ContainsFunc(h.Probes, func(p TraceProbe) bool { return p.reached(tp) })
+}
+
+// reached reports whether the probe got an answer from the destination (an
+// echo reply or a TCP reply) or a destination unreachable error.
+func (p TraceProbe) reached(tp traceProto) bool {
+	if p.Timeout {
+		return false
 	}
+	return p.PortState != "" || p.ICMPType == icmpTypeNumber(tp.echoReply) || p.ICMPType == icmpTypeNumber(tp.unreachable)
+}
 
-	return Result{
-		TimeStamp: time.Now(),
-		ProbeType: "trace",
-		Target:    t.Host,
-		TraceData: traceData,
-		Message:   "Trace complete",
-		Severity:  SeverityOK,
-		Success:   true,
-		Latency:   time.Since(startTime),
-	}, nil
+// resolveProbeNames fills in the reverse DNS name of every address that
+// answered a probe on the path, looking each one up once.
+func resolveProbeNames(ctx context.Context, path [][]TraceProbe) {
+	var ips []string
+	for _, probes := range path {
+		for _, p := range probes {
+			if !p.Timeout && !slices.Contains(ips, p.IP) {
+				ips = append(ips, p.IP)
+			}
+		}
+	}
+
+	names := lookupNames(ctx, ips)
+	for _, probes := range path {
+		for i := range probes {
+			probes[i].HostName = names[probes[i].IP]
+		}
+	}
+}
+
+// lookupNames returns the reverse DNS name of each of ips that has one,
+// running up to traceLookups lookups at once so that slow or silent
+// resolvers for different hops overlap.
+func lookupNames(ctx context.Context, ips []string) map[string]string {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		names = make(map[string]string, len(ips))
+		sem   = make(chan struct{}, traceLookups)
+	)
+
+	for _, ip := range ips {
+		wg.Add(1)
+		go func(ip string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			found, err := net.DefaultResolver.LookupAddr(ctx, ip)
+			if err != nil || len(found) == 0 {
+				return
+			}
+			mu.Lock()
+			names[ip] = found[0]
+			mu.Unlock()
+		}(ip)
+	}
+	wg.Wait()
+
+	return names
+}
+
+// traceMatch is an ICMP message that may answer a trace probe.
+type traceMatch struct {
+	icmpType int
+	code     int
+	// proto and header identify the probe: for an echo reply, the ICMP
+	// protocol and the reply's own header; for an error, the protocol and
+	// first 8 bytes of the quoted datagram's transport header.
+	proto  int
+	header []byte
+}
+
+// matchTraceReply parses an ICMP message received while tracing to dst. It
+// accepts echo replies, and Time Exceeded and Destination Unreachable
+// errors quoting a datagram sent to dst. Anything else is reported as not
+// ok.
+func matchTraceReply(tp traceProto, b []byte, dst net.IP) (traceMatch, bool) {
+	msg, err := icmp.ParseMessage(tp.proto, b)
+	if err != nil || len(b) < 8 {
+		return traceMatch{}, false
+	}
+	m := traceMatch{icmpType: icmpTypeNumber(msg.Type), code: msg.Code}
+
+	var quoted []byte
+	switch body := msg.Body.(type) {
+	case *icmp.Echo:
+		if msg.Type != tp.echoReply {
+			return traceMatch{}, false
+		}
+		m.proto, m.header = tp.proto, b[:8]
+		return m, true
+	case *icmp.TimeExceeded:
+		quoted = body.Data
+	case *icmp.DstUnreach:
+		quoted = body.Data
+	default:
+		return traceMatch{}, false
+	}
+
+	proto, inner, ok := quotedHeader(tp, quoted, dst)
+	if !ok || len(inner) < 8 {
+		return traceMatch{}, false
+	}
+	// A quoted ICMP datagram must be one of our echo requests, not, say, a
+	// reply we sent.
+	if proto == tp.proto && int(inner[0]) != icmpTypeNumber(tp.echo) {
+		return traceMatch{}, false
+	}
+	m.proto, m.header = proto, inner[:8]
+	return m, true
+}
+
+// quotedHeader strips the IP header from the datagram quoted in an ICMP
+// error and returns its protocol and payload, if it was sent to dst.
+func quotedHeader(tp traceProto, b []byte, dst net.IP) (int, []byte, bool) {
+	if tp.proto == 58 {
+		if len(b) < ipv6.HeaderLen || b[0]>>4 != 6 || !bytes.Equal(b[24:40], dst.To16()) {
+			return 0, nil, false
+		}
+		return int(b[6]), b[ipv6.HeaderLen:], true
+	}
+
+	if len(b) < ipv4.HeaderLen || b[0]>>4 != 4 {
+		return 0, nil, false
+	}
+	ihl := int(b[0]&0x0f) * 4
+	if ihl < ipv4.HeaderLen || len(b) < ihl || !bytes.Equal(b[16:20], dst.To4()) {
+		return 0, nil, false
+	}
+	return int(b[9]), b[ihl:], true
+}
+
+// icmpTypeNumber returns the on-the-wire value of an ICMP or ICMPv6 type.
+func icmpTypeNumber(t icmp.Type) int {
+	switch t := t.(type) {
+	case ipv4.ICMPType:
+		return int(t)
+	case ipv6.ICMPType:
+		return int(t)
+	}
+	return -1
+}
+
+// peerIP extracts the address from a packet source.
+func peerIP(addr net.Addr) net.IP {
+	if a, ok := addr.(*net.IPAddr); ok {
+		return a.IP
+	}
+	return net.ParseIP(addr.String())
 }
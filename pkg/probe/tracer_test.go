@@ -0,0 +1,429 @@
+package probe
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// quotedProbe builds the IP header and echo request an ICMP error quotes.
+func quotedProbe(t *testing.T, tp traceProto, proto int, dst net.IP, id, seq int) []byte {
+	t.Helper()
+	echo, err := (&icmp.Message{Type: tp.echo, Body: &icmp.Echo{ID: id, Seq: seq}}).Marshal(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tp.proto == 58 {
+		hdr := make([]byte, ipv6.HeaderLen)
+		hdr[0] = 6 << 4
+		hdr[6] = byte(proto)
+		copy(hdr[8:24], net.ParseIP("fd00::2"))
+		copy(hdr[24:40], dst.To16())
+		return append(hdr, echo[:8]...)
+	}
+
+	hdr, err := (&ipv4.Header{
+		Version:  4,
+		Len:      ipv4.HeaderLen,
+		TotalLen: ipv4.HeaderLen + len(echo),
+		TTL:      1,
+		Protocol: proto,
+		Src:      net.IPv4(192, 0, 2, 1),
+		Dst:      dst,
+	}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(hdr, echo[:8]...)
+}
+
+func marshalICMP(t *testing.T, m icmp.Message) []byte {
+	t.Helper()
+	b, err := m.Marshal(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestMatchTraceReply(t *testing.T) {
+	// matched is a traceMatch with the ID and sequence fields of its header
+	// decoded, as they sit in an ICMP echo.
+	type matched struct {
+		icmpType, code, proto, id, seq int
+	}
+
+	dst4 := net.IPv4(198, 51, 100, 7)
+	dst6 := net.ParseIP("2001:db8::7")
+	other := net.IPv4(198, 51, 100, 8)
+
+	tests := []struct {
+		name   string
+		tp     traceProto
+		dst    net.IP
+		packet []byte
+		want   matched
+		ok     bool
+	}{
+		{
+			name:   "Echo reply",
+			tp:     traceICMPv4,
+			dst:    dst4,
+			packet: marshalICMP(t, icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: &icmp.Echo{ID: 77, Seq: 5}}),
+			want:   matched{icmpType: 0, proto: 1, id: 77, seq: 5},
+			ok:     true,
+		},
+		{
+			name: "Time exceeded quoting our probe",
+			tp:   traceICMPv4,
+			dst:  dst4,
+			packet: marshalICMP(t, icmp.Message{
+				Type: ipv4.ICMPTypeTimeExceeded,
+				Body: &icmp.TimeExceeded{Data: quotedProbe(t, traceICMPv4, 1, dst4, 77, 6)},
+			}),
+			want: matched{icmpType: 11, proto: 1, id: 77, seq: 6},
+			ok:   true,
+		},
+		{
+			name: "Unreachable keeps its code",
+			tp:   traceICMPv4,
+			dst:  dst4,
+			packet: marshalICMP(t, icmp.Message{
+				Type: ipv4.ICMPTypeDestinationUnreachable,
+				Code: 13,
+				Body: &icmp.DstUnreach{Data: quotedProbe(t, traceICMPv4, 1, dst4, 77, 7)},
+			}),
+			want: matched{icmpType: 3, code: 13, proto: 1, id: 77, seq: 7},
+			ok:   true,
+		},
+		{
+			name: "Quoted probe to another host",
+			tp:   traceICMPv4,
+			dst:  dst4,
+			packet: marshalICMP(t, icmp.Message{
+				Type: ipv4.ICMPTypeTimeExceeded,
+				Body: &icmp.TimeExceeded{Data: quotedProbe(t, traceICMPv4, 1, other, 77, 6)},
+			}),
+		},
+		{
+			name: "Quoted UDP datagram",
+			tp:   traceICMPv4,
+			dst:  dst4,
+			packet: marshalICMP(t, icmp.Message{
+				Type: ipv4.ICMPTypeTimeExceeded,
+				Body: &icmp.TimeExceeded{Data: quotedProbe(t, traceICMPv4, 17, dst4, 77, 6)},
+			}),
+			want: matched{icmpType: 11, proto: 17, id: 77, seq: 6},
+			ok:   true,
+		},
+		{
+			name:   "Our own echo request looped back",
+			tp:     traceICMPv4,
+			dst:    dst4,
+			packet: marshalICMP(t, icmp.Message{Type: ipv4.ICMPTypeEcho, Body: &icmp.Echo{ID: 77, Seq: 5}}),
+		},
+		{
+			name:   "Truncated quote",
+			tp:     traceICMPv4,
+			dst:    dst4,
+			packet: marshalICMP(t, icmp.Message{Type: ipv4.ICMPTypeTimeExceeded, Body: &icmp.TimeExceeded{Data: []byte{0x45, 0}}}),
+		},
+		{
+			name: "ICMPv6 time exceeded",
+			tp:   traceICMPv6,
+			dst:  dst6,
+			packet: marshalICMP(t, icmp.Message{
+				Type: ipv6.ICMPTypeTimeExceeded,
+				Body: &icmp.TimeExceeded{Data: quotedProbe(t, traceICMPv6, 58, dst6, 77, 9)},
+			}),
+			want: matched{icmpType: 3, proto: 58, id: 77, seq: 9},
+			ok:   true,
+		},
+		{
+			name:   "ICMPv6 echo reply",
+			tp:     traceICMPv6,
+			dst:    dst6,
+			packet: marshalICMP(t, icmp.Message{Type: ipv6.ICMPTypeEchoReply, Body: &icmp.Echo{ID: 77, Seq: 10}}),
+			want:   matched{icmpType: 129, proto: 58, id: 77, seq: 10},
+			ok:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := matchTraceReply(tt.tp, tt.packet, tt.dst)
+			var got matched
+			if ok {
+				got = matched{
+					icmpType: m.icmpType,
+					code:     m.code,
+					proto:    m.proto,
+					id:       int(binary.BigEndian.Uint16(m.header[4:6])),
+					seq:      int(binary.BigEndian.Uint16(m.header[6:8])),
+				}
+			}
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("matchTraceReply() = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNextTraceSeqUnique(t *testing.T) {
+	seen := make(map[int]bool)
+	for i := 0; i < 1000; i++ {
+		seq := nextTraceSeq()
+		if seen[seq] {
+			t.Fatalf("sequence %d handed out twice", seq)
+		}
+		seen[seq] = true
+	}
+}
+
+func TestNewTraceHop(t *testing.T) {
+	hop := newTraceHop(4, []TraceProbe{
+		{IP: "10.0.0.1", HostName: "a.example.", RTT: 2 * time.Millisecond, ICMPType: 11},
+		{Timeout: true},
+		{IP: "10.0.0.2", RTT: 6 * time.Millisecond, ICMPType: 11},
+		{IP: "10.0.0.1", RTT: 4 * time.Millisecond, ICMPType: 11},
+	})
+
+	if hop.HopNumber != 4 || hop.Timeout || hop.IP != "10.0.0.1" || hop.HostName != "a.example." || hop.ICMPType != 11 {
+		t.Errorf("hop = %+v", hop)
+	}
+	if !reflect.DeepEqual(hop.IPs, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("IPs = %v, want both responders in order", hop.IPs)
+	}
+	if hop.Loss != 25 {
+		t.Errorf("Loss = %v, want 25", hop.Loss)
+	}
+	if hop.MinRTT != 2*time.Millisecond || hop.AvgRTT != 4*time.Millisecond || hop.MaxRTT != 6*time.Millisecond || hop.RTT != hop.AvgRTT {
+		t.Errorf("RTTs = %v/%v/%v (RTT %v)", hop.MinRTT, hop.AvgRTT, hop.MaxRTT, hop.RTT)
+	}
+
+	silent := newTraceHop(5, []TraceProbe{{Timeout: true}, {Timeout: true}})
+	if !silent.Timeout || silent.IP != "*" || silent.Loss != 100 || silent.IPs != nil {
+		t.Errorf("silent hop = %+v", silent)
+	}
+}
+
+func TestTraceHopReached(t *testing.T) {
+	transit := newTraceHop(1, []TraceProbe{{IP: "10.0.0.1", ICMPType: 11}})
+	if transit.reached(traceICMPv4) {
+		t.Error("time exceeded counted as reaching the destination")
+	}
+
+	// A timed-out probe carries ICMPType 0, which is an IPv4 echo reply.
+	if newTraceHop(2, []TraceProbe{{Timeout: true}}).reached(traceICMPv4) {
+		t.Error("timeout counted as reaching the destination")
+	}
+
+	arrived := newTraceHop(3, []TraceProbe{{Timeout: true}, {IP: "10.0.0.9", ICMPType: 0}})
+	if !arrived.reached(traceICMPv4) {
+		t.Error("echo reply did not reach the destination")
+	}
+	if !newTraceHop(3, []TraceProbe{{IP: "2001:db8::1", ICMPType: 1, ICMPCode: 3}}).reached(traceICMPv6) {
+		t.Error("ICMPv6 unreachable did not end the trace")
+	}
+}
+
+func TestBuildTraceUDP(t *testing.T) {
+	src, dst := net.IPv4(192, 0, 2, 1), net.IPv4(198, 51, 100, 7)
+	first := buildTraceUDP(src, dst, 40000, 33434, 1)
+
+	for _, seq := range []uint16{1, 2, 0x1234, 0xfffe} {
+		seg := buildTraceUDP(src, dst, 40000, 33434, seq)
+		if got := binary.BigEndian.Uint16(seg[6:8]); got != seq {
+			t.Errorf("seq %d: checksum field = %d", seq, got)
+		}
+		// A valid checksum sums, with the field itself, to zero.
+		if got := transportChecksum(17, src, dst, seg); got != 0 {
+			t.Errorf("seq %d: checksum does not verify (%#04x)", seq, got)
+		}
+		if !reflect.DeepEqual(seg[:6], first[:6]) || len(seg) != len(first) {
+			t.Errorf("seq %d: ports or length changed: % x", seq, seg[:6])
+		}
+	}
+}
+
+func TestTraceICMPChecksumConstant(t *testing.T) {
+	s := &traceSession{tp: traceICMPv4, method: "icmp"}
+	first := s.buildProbe(1)
+	for _, seq := range []int{2, 300, 0xffff} {
+		b := s.buildProbe(seq)
+		if !reflect.DeepEqual(b[:4], first[:4]) {
+			t.Errorf("seq %d: type, code and checksum % x differ from % x", seq, b[:4], first[:4])
+		}
+		m, err := icmp.ParseMessage(1, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if echo, ok := m.Body.(*icmp.Echo); !ok || echo.Seq != seq {
+			t.Errorf("seq %d: body = %+v", seq, m.Body)
+		}
+	}
+}
+
+func TestParseTraceTCPReply(t *testing.T) {
+	reply := func(srcPort, dstPort int, ack uint32, flags byte) []byte {
+		seg := make([]byte, 20)
+		binary.BigEndian.PutUint16(seg[0:2], uint16(srcPort))
+		binary.BigEndian.PutUint16(seg[2:4], uint16(dstPort))
+		binary.BigEndian.PutUint32(seg[8:12], ack)
+		seg[12] = 5 << 4
+		seg[13] = flags
+		return seg
+	}
+	ack := traceTCPSeq(42) + 1
+
+	tests := []struct {
+		name  string
+		seg   []byte
+		seq   int
+		state PortState
+		ok    bool
+	}{
+		{"SYN-ACK", reply(443, 40000, ack, tcpFlagSYN|tcpFlagACK), 42, PortOpen, true},
+		{"RST-ACK", reply(443, 40000, ack, tcpFlagRST|tcpFlagACK), 42, PortClosed, true},
+		{"RST without ACK", reply(443, 40000, 0, tcpFlagRST), 0, "", false},
+		{"Other flow", reply(443, 40001, ack, tcpFlagSYN|tcpFlagACK), 0, "", false},
+		{"Another process", reply(443, 40000, uint32(traceEchoID+1)<<16|43, tcpFlagSYN|tcpFlagACK), 0, "", false},
+		{"Truncated", reply(443, 40000, ack, tcpFlagSYN|tcpFlagACK)[:12], 0, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq, state, ok := parseTraceTCPReply(tt.seg, 40000, 443)
+			if seq != tt.seq || state != tt.state || ok != tt.ok {
+				t.Errorf("parseTraceTCPReply() = %d, %q, %v; want %d, %q, %v", seq, state, ok, tt.seq, tt.state, tt.ok)
+			}
+		})
+	}
+}
+
+func TestProbeSeq(t *testing.T) {
+	header := func(a, b uint16, c uint32) []byte {
+		h := make([]byte, 8)
+		binary.BigEndian.PutUint16(h[0:2], a)
+		binary.BigEndian.PutUint16(h[2:4], b)
+		binary.BigEndian.PutUint32(h[4:8], c)
+		return h
+	}
+	udp := &traceSession{tp: traceICMPv4, method: "udp", srcPort: 40000, dstPort: 33434}
+	tcp := &traceSession{tp: traceICMPv4, method: "tcp", srcPort: 40000, dstPort: 443}
+	echo := &traceSession{tp: traceICMPv4, method: "icmp"}
+
+	tests := []struct {
+		name string
+		s    *traceSession
+		m    traceMatch
+		seq  int
+		ok   bool
+	}{
+		{"UDP checksum", udp, traceMatch{proto: 17, header: header(40000, 33434, 23<<16|9)}, 9, true},
+		{"UDP from another flow", udp, traceMatch{proto: 17, header: header(40001, 33434, 23<<16|9)}, 0, false},
+		{"TCP sequence", tcp, traceMatch{proto: 6, header: header(40000, 443, traceTCPSeq(9))}, 9, true},
+		{"TCP quote to UDP session", udp, traceMatch{proto: 6, header: header(40000, 33434, traceTCPSeq(9))}, 0, false},
+		{"Echo", echo, traceMatch{proto: 1, header: header(8<<8, 0, uint32(traceEchoID)<<16|9)}, 9, true},
+		{"Echo from another process", echo, traceMatch{proto: 1, header: header(8<<8, 0, uint32(traceEchoID+1)<<16|9)}, 0, false},
+		{"UDP quote to echo session", echo, traceMatch{proto: 17, header: header(40000, 33434, uint32(traceEchoID)<<16|9)}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq, ok := tt.s.probeSeq(tt.m)
+			if seq != tt.seq || ok != tt.ok {
+				t.Errorf("probeSeq() = %d, %v; want %d, %v", seq, ok, tt.seq, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTraceReplyDemux(t *testing.T) {
+	s := &traceSession{timeout: 50 * time.Millisecond, pending: make(map[int]chan traceReply)}
+	flight := func(seq int, start time.Time) *traceFlight {
+		f := &traceFlight{seq: seq, start: start, reply: make(chan traceReply, 1)}
+		s.pending[seq] = f.reply
+		return f
+	}
+	now := time.Now()
+
+	a, b := flight(1, now), flight(2, now)
+	// Replies arrive out of order, with a duplicate and a stray.
+	s.deliver(traceReply{seq: 2, probe: TraceProbe{IP: "10.0.0.2"}, at: now.Add(2 * time.Millisecond)})
+	s.deliver(traceReply{seq: 9, probe: TraceProbe{IP: "10.0.0.9"}, at: now})
+	s.deliver(traceReply{seq: 1, probe: TraceProbe{IP: "10.0.0.1"}, at: now.Add(time.Millisecond)})
+	s.deliver(traceReply{seq: 1, probe: TraceProbe{IP: "10.0.0.3"}, at: now})
+
+	for _, tt := range []struct {
+		f   *traceFlight
+		ip  string
+		rtt time.Duration
+	}{{a, "10.0.0.1", time.Millisecond}, {b, "10.0.0.2", 2 * time.Millisecond}} {
+		p, err := s.awaitReply(context.Background(), tt.f)
+		if err != nil || p.IP != tt.ip || p.RTT != tt.rtt {
+			t.Errorf("seq %d: got %+v, %v; want %s after %v", tt.f.seq, p, err, tt.ip, tt.rtt)
+		}
+	}
+	if len(s.pending) != 0 {
+		t.Errorf("pending = %v after both replies", s.pending)
+	}
+
+	// A reply that arrived in time counts even when waiting for it starts
+	// after the deadline; one that arrived after the deadline does not.
+	sent := now.Add(-time.Second)
+	early := flight(3, sent)
+	s.deliver(traceReply{seq: 3, probe: TraceProbe{IP: "10.0.0.4"}, at: sent.Add(10 * time.Millisecond)})
+	if p, _ := s.awaitReply(context.Background(), early); p.Timeout || p.RTT != 10*time.Millisecond {
+		t.Errorf("buffered reply within the timeout = %+v, want a 10ms reply", p)
+	}
+	late := flight(6, sent)
+	s.deliver(traceReply{seq: 6, probe: TraceProbe{IP: "10.0.0.4"}, at: now})
+	if p, _ := s.awaitReply(context.Background(), late); !p.Timeout {
+		t.Errorf("reply after the timeout = %+v, want a timeout", p)
+	}
+
+	if p, err := s.awaitReply(context.Background(), flight(4, time.Now())); err != nil || !p.Timeout {
+		t.Errorf("unanswered probe = %+v, %v; want a timeout", p, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.awaitReply(ctx, flight(5, time.Now())); err == nil {
+		t.Error("cancelled wait returned no error")
+	}
+}
+
+func TestLookupNamesCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if names := lookupNames(ctx, []string{"192.0.2.1", "192.0.2.2"}); len(names) != 0 {
+		t.Errorf("lookupNames() = %v after cancellation", names)
+	}
+}
+
+func TestTraceMaxHopsInvalid(t *testing.T) {
+	for _, hops := range []int{-1, 0, 256} {
+		probers := []Prober{
+			&TraceProber{Host: "127.0.0.1", MaxHops: hops, Timeout: time.Second},
+			&MTRProber{Host: "127.0.0.1", MaxHops: hops, Timeout: time.Second, Cycles: 1},
+		}
+		for _, p := range probers {
+			res, err := p.Probe(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Success || res.Severity != SeverityError {
+				t.Errorf("%s with MaxHops %d: %+v, want an error result", p.Type(), hops, res)
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package probe
 
 import (
 	"context"
+	"sort"
 	"time"
 )
 
@@ -44,12 +45,16 @@ type Result struct {
 	PingData      *PingData      `json:"ping_data,omitempty"`
 	ScanData      *ScanData      `json:"scan_data,omitempty"`
 	TraceData     *TraceData     `json:"trace_data,omitempty"`
+	MTRData       *MTRData       `json:"mtr_data,omitempty"`
 	HTTPData      *HTTPData      `json:"http_data,omitempty"`
 	DNSData       *DNSData       `json:"dns_data,omitempty"`
 	DiscoverData  *DiscoverData  `json:"discover_data,omitempty"`
 	SpeedTestData *SpeedTestData `json:"speedtest_data,omitempty"`
 	WhoisData     *WhoisData     `json:"whois_data,omitempty"`
 
+	// Analysis
+	Anomaly *AnomalyData `json:"anomaly,omitempty"`
+
 	// Outcome
 	Message  string   `json:"message"`
 	Severity Severity `json:"severity"`
@@ -69,14 +74,116 @@ type PingData struct {
 	MaxRTT      time.Duration `json:"max_rtt"`
 	AvgRTT      time.Duration `json:"avg_rtt"`
 	StdDevRTT   time.Duration `json:"stdDev_rtt"`
+	// Mode is how the host was pinged; see DetectPingMode.
+	Mode PingMode `json:"mode,omitempty"`
 }
 
 // ScanData contains information about a port scan probe.
 type ScanData struct {
-	ScanRateMs int64  `json:"scan_rate_ms"`
-	TotalPorts int    `json:"total_ports"`
-	OpenPorts  []int  `json:"open_ports"`
-	ScanMethod string `json:"scan_method"`
+	// ScanRateMs is ports per millisecond; PortsPerSec is the same rate per
+	// second.
+	ScanRateMs  float64       `json:"scan_rate_ms"`
+	PortsPerSec float64       `json:"ports_per_sec"`
+	Elapsed     time.Duration `json:"elapsed"`
+	// Address is the IP address the host name resolved to and the ports
+	// were probed at.
+	Address string `json:"address,omitempty"`
+	// RTT, AdaptiveTimeout and Concurrency are the smoothed round-trip time,
+	// the derived per-port timeout and the final in-flight window of an
+	// adaptive scan.
+	RTT             time.Duration `json:"rtt,omitempty"`
+	AdaptiveTimeout time.Duration `json:"adaptive_timeout,omitempty"`
+	Concurrency     int           `json:"concurrency,omitempty"`
+	// Timeouts and Refused count ports that timed out and ports that
+	// answered with a RST.
+	Timeouts         int   `json:"timeouts"`
+	Refused          int   `json:"refused"`
+	TotalPorts       int   `json:"total_ports"`
+	OpenPorts        []int `json:"open_ports"`
+	ClosedPorts      []int `json:"closed_ports,omitempty"`
+	FilteredPorts    []int `json:"filtered_ports,omitempty"`
+	UnreachablePorts []int `json:"unreachable_ports,omitempty"`
+	// OpenFilteredPorts are UDP ports that stayed silent: either open and
+	// ignoring the probe, or filtered.
+	OpenFilteredPorts []int  `json:"open_filtered_ports,omitempty"`
+	Protocol          string `json:"protocol,omitempty"`
+	ScanMethod        string `json:"scan_method"`
+	// Services is filled by the optional service detection pass.
+	Services []ServiceInfo `json:"services,omitempty"`
+	// Drift is set when the scan was compared against a baseline.
+	Drift *ScanDrift `json:"drift,omitempty"`
+}
+
+// PortState classifies the outcome of probing a single port.
+type PortState string
+
+const (
+	// PortOpen means the port accepted the connection (or answered SYN-ACK).
+	PortOpen PortState = "open"
+	// PortClosed means the host actively refused (TCP RST / ECONNREFUSED).
+	PortClosed PortState = "closed"
+	// PortFiltered means no answer arrived before the timeout, typically a
+	// firewall silently dropping packets.
+	PortFiltered PortState = "filtered"
+	// PortUnreachable means an ICMP host or network unreachable error came
+	// back instead of a TCP answer.
+	PortUnreachable PortState = "unreachable"
+	// PortOpenFiltered means a UDP port gave no answer at all; open and
+	// filtered ports are indistinguishable in that case.
+	PortOpenFiltered PortState = "open|filtered"
+)
+
+// Ports returns the ports recorded in the given state.
+func (d *ScanData) Ports(state PortState) []int {
+	switch state {
+	case PortOpen:
+		return d.OpenPorts
+	case PortClosed:
+		return d.ClosedPorts
+	case PortFiltered:
+		return d.FilteredPorts
+	case PortUnreachable:
+		return d.UnreachablePorts
+	case PortOpenFiltered:
+		return d.OpenFilteredPorts
+	default:
+		return nil
+	}
+}
+
+// add records port under state.
+func (d *ScanData) add(port int, state PortState) {
+	switch state {
+	case PortOpen:
+		d.OpenPorts = append(d.OpenPorts, port)
+	case PortClosed:
+		d.ClosedPorts = append(d.ClosedPorts, port)
+	case PortFiltered:
+		d.FilteredPorts = append(d.FilteredPorts, port)
+	case PortUnreachable:
+		d.UnreachablePorts = append(d.UnreachablePorts, port)
+	case PortOpenFiltered:
+		d.OpenFilteredPorts = append(d.OpenFilteredPorts, port)
+	}
+}
+
+// setRate records the scan duration and rate for done ports.
+func (d *ScanData) setRate(done int, elapsed time.Duration) {
+	d.Elapsed = elapsed
+	if elapsed <= 0 {
+		return
+	}
+	d.PortsPerSec = float64(done) / elapsed.Seconds()
+	d.ScanRateMs = d.PortsPerSec / 1000
+}
+
+// sortPorts orders every state list ascending.
+func (d *ScanData) sortPorts() {
+	sort.Ints(d.OpenPorts)
+	sort.Ints(d.ClosedPorts)
+	sort.Ints(d.FilteredPorts)
+	sort.Ints(d.UnreachablePorts)
+	sort.Ints(d.OpenFilteredPorts)
 }
 
 // DiscoverDevice represents a single active device found on the network.
@@ -91,6 +198,8 @@ type DiscoverData struct {
 	LocalIP string           `json:"local_ip"`
 	Prefix  string           `json:"prefix"`
 	Devices []DiscoverDevice `json:"devices"`
+	// Mode is how hosts were pinged; see DetectPingMode.
+	Mode PingMode `json:"mode,omitempty"`
 }
 
 // WhoisData contains raw WHOIS response.
@@ -98,17 +207,85 @@ type WhoisData struct {
 	Raw string `json:"raw"`
 }
 
+// TraceHop is one hop of a traceroute. A hop is probed one or more times:
+// IP, HostName, ICMPType and ICMPCode come from the first reply, RTT is the
+// average, and Timeout is set only when no probe was answered.
 type TraceHop struct {
 	IP        string        `json:"ip"`
 	HostName  string        `json:"host_name"`
 	RTT       time.Duration `json:"rtt"`
 	HopNumber int           `json:"hop_number"`
 	Timeout   bool          `json:"timeout"`
+	// ICMPType and ICMPCode are from the reply, e.g. time exceeded (11/0
+	// for IPv4, 3/0 for IPv6) or destination unreachable with its code.
+	// Both are zero for a hop that timed out.
+	ICMPType int `json:"icmp_type"`
+	ICMPCode int `json:"icmp_code"`
+	// IPs lists every address that answered, in order of first reply;
+	// several mean the path is load balanced.
+	IPs    []string      `json:"ips,omitempty"`
+	Loss   float64       `json:"loss"`
+	MinRTT time.Duration `json:"min_rtt"`
+	AvgRTT time.Duration `json:"avg_rtt"`
+	MaxRTT time.Duration `json:"max_rtt"`
+	// Probes holds each probe's outcome in the order they were sent.
+	Probes []TraceProbe `json:"probes,omitempty"`
+}
+
+// TraceProbe is the outcome of a single probe to a hop.
+type TraceProbe struct {
+	IP       string        `json:"ip,omitempty"`
+	HostName string        `json:"host_name,omitempty"`
+	RTT      time.Duration `json:"rtt"`
+	Timeout  bool          `json:"timeout"`
+	ICMPType int           `json:"icmp_type"`
+	ICMPCode int           `json:"icmp_code"`
+	// PortState is set when the destination answered a TCP probe itself:
+	// open for a SYN-ACK, closed for a RST.
+	PortState PortState `json:"port_state,omitempty"`
 }
 
 // TraceData contains the sequence of hops from a traceroute probe.
 type TraceData struct {
-	Hops []TraceHop `json:"hops"`
+	// Destination is the address the trace was sent to.
+	Destination string `json:"destination,omitempty"`
+	// Method is the probe type ("icmp", "udp" or "tcp") and Port the
+	// destination port of UDP and TCP probes.
+	Method string     `json:"method,omitempty"`
+	Port   int        `json:"port,omitempty"`
+	Hops   []TraceHop `json:"hops"`
+}
+
+// MTRData holds the per-hop statistics of an MTR run: the path traced
+// again and again, one probe per hop each cycle.
+type MTRData struct {
+	Destination string   `json:"destination"`
+	Method      string   `json:"method"`
+	Port        int      `json:"port,omitempty"`
+	Cycles      int      `json:"cycles"`
+	Hops        []MTRHop `json:"hops"`
+}
+
+// MTRHop accumulates one hop's replies across MTR cycles. Last, Avg, Best,
+// Worst and StdDev cover the replies received; they are zero until the hop
+// answers.
+type MTRHop struct {
+	HopNumber int    `json:"hop_number"`
+	HostName  string `json:"host_name"`
+	// IPs lists every address that answered, in order of first reply.
+	IPs      []string      `json:"ips,omitempty"`
+	Sent     int           `json:"sent"`
+	Received int           `json:"received"`
+	Loss     float64       `json:"loss"`
+	Last     time.Duration `json:"last"`
+	Avg      time.Duration `json:"avg"`
+	Best     time.Duration `json:"best"`
+	Worst    time.Duration `json:"worst"`
+	StdDev   time.Duration `json:"stddev"`
+
+	// mean and m2 are the running mean and sum of squared deviations from
+	// it, kept unrounded for Welford's method.
+	mean, m2 float64
 }
 
 // DNSRecord represents a single DNS record.
@@ -147,15 +324,25 @@ type SpeedTestData struct {
 	UploadMbps   float64 `json:"upload_mbps,omitempty"`
 }
 
+// AnomalyData describes how far a result's latency deviated from the
+// baseline of previous results for the same target.
+type AnomalyData struct {
+	ZScore   float64       `json:"z_score"`
+	Latency  time.Duration `json:"latency"`
+	Baseline time.Duration `json:"baseline"`
+	StdDev   time.Duration `json:"stddev"`
+	Reason   string        `json:"reason"`
+}
+
 // Prober defines the interface that all network probes must implement.
 type Prober interface {
 	Probe(ctx context.Context) (Result, error)
 	Type() string
 }
 
-// IsAnomaly returns true if the probe result indicates an anomalous state.
-// This is a stub for Phase 4 (Anomaly Detection & Notifications).
+// IsAnomaly returns true if the probe result indicates an anomalous state:
+// a failure, a non-OK severity, or a statistical deviation flagged by an
+// anomaly detector.
 func (r *Result) IsAnomaly() bool {
-	// Simple heuristic for now: anything that isn't OK or failed entirely.
-	return r.Severity == SeverityWarning || r.Severity == SeverityError || !r.Success
+	return r.Anomaly != nil || r.Severity == SeverityWarning || r.Severity == SeverityError || !r.Success
 }
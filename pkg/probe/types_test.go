@@ -2,6 +2,7 @@ package probe
 
 import (
 	"testing"
+	"time"
 )
 
 func TestSeverityString(t *testing.T) {
@@ -25,3 +26,25 @@ func TestSeverityString(t *testing.T) {
 		})
 	}
 }
+
+func TestIsAnomaly(t *testing.T) {
+	tests := []struct {
+		name   string
+		result Result
+		want   bool
+	}{
+		{"Healthy", Result{Success: true, Severity: SeverityOK}, false},
+		{"Warning", Result{Success: true, Severity: SeverityWarning}, true},
+		{"Failed", Result{Success: false, Severity: SeverityError}, true},
+		{"Latency spike", Result{Success: true, Severity: SeverityOK,
+			Anomaly: &AnomalyData{ZScore: 8, Latency: 120 * time.Millisecond}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.IsAnomaly(); got != tt.want {
+				t.Errorf("IsAnomaly() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
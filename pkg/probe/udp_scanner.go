@@ -0,0 +1,255 @@
+package probe
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultUDPRate is the probe rate used when UDPScanner.Rate is zero. Most
+// kernels rate-limit ICMP port unreachable replies (Linux to roughly 1000/s,
+// some BSDs to 200/s), so sending faster turns closed ports into silence.
+const DefaultUDPRate = 100
+
+// UDPScanner sends a datagram to each port and classifies the port from the
+// answer: any reply is open, ICMP port unreachable is closed and silence is
+// open|filtered. Well-known ports get a protocol-specific payload (DNS, NTP,
+// SNMP) so the service actually answers; others get an empty datagram.
+type UDPScanner struct {
+	Host        string
+	Ports       PortSet
+	Timeout     time.Duration
+	Concurrency int
+	// Rate caps probes per second across all workers. Zero uses
+	// DefaultUDPRate. Ignored when Limiter is set.
+	Rate int
+	// Limiter, if set, paces probes instead of Rate and may be shared
+	// across scanners.
+	Limiter *RateLimiter
+	// Retries is the number of extra datagrams sent to silent ports.
+	Retries int
+	// Sem, if set, is shared with other scanners and bounds in-flight
+	// ports across all of them.
+	Sem Semaphore
+	// Progress, if set, is called after every port from a single goroutine.
+	Progress func(ScanProgress)
+	// Family restricts a host name to its IPv4 or IPv6 addresses.
+	Family Family
+}
+
+func (u *UDPScanner) Type() string {
+	return "scan"
+}
+
+func (u *UDPScanner) Probe(ctx context.Context) (Result, error) {
+	startTime := time.Now()
+
+	addr, failed := resolveScan(ctx, u.Host, u.Family)
+	if addr == nil {
+		return failed, nil
+	}
+
+	limiter := u.Limiter
+	if limiter == nil {
+		rate := u.Rate
+		if rate <= 0 {
+			rate = DefaultUDPRate
+		}
+		limiter = NewRateLimiter(rate)
+	}
+
+	type portResult struct {
+		port  int
+		state PortState
+	}
+
+	results := make(chan portResult)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for range max(u.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for port := range jobs {
+				if u.Sem != nil {
+					if u.Sem.Acquire(ctx) != nil {
+						continue
+					}
+				}
+				state := u.probePort(ctx, limiter, addr, port)
+				if u.Sem != nil {
+					u.Sem.Release()
+				}
+				if ctx.Err() != nil {
+					continue
+				}
+				results <- portResult{port: port, state: state}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for port := range u.Ports.All() {
+			select {
+			case jobs <- port:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	data := &ScanData{
+		Address:    addr.String(),
+		TotalPorts: u.Ports.Len(),
+		Protocol:   "udp",
+		ScanMethod: "udp",
+	}
+	done := 0
+	for r := range results {
+		data.add(r.port, r.state)
+		done++
+
+		if u.Progress != nil {
+			u.Progress(ScanProgress{
+				Host:    u.Host,
+				Port:    r.port,
+				State:   r.state,
+				Done:    done,
+				Total:   data.TotalPorts,
+				Open:    len(data.OpenPorts),
+				Elapsed: time.Since(startTime),
+			})
+		}
+	}
+	data.sortPorts()
+	data.Timeouts = len(data.OpenFilteredPorts)
+	data.Refused = len(data.ClosedPorts)
+
+	duration := time.Since(startTime)
+	data.setRate(done, duration)
+
+	return Result{
+		Target:    u.Host,
+		TimeStamp: time.Now(),
+		ProbeType: "scan",
+		Success:   true,
+		Severity:  SeverityOK,
+		Message:   fmt.Sprintf("Found %d open UDP ports", len(data.OpenPorts)),
+		ScanData:  data,
+		Latency:   duration,
+	}, nil
+}
+
+// probePort sends up to 1+Retries datagrams to port on addr, waiting on the
+// rate limiter before each one.
+func (u *UDPScanner) probePort(ctx context.Context, limiter *RateLimiter, addr net.IP, port int) PortState {
+	dialer := net.Dialer{Timeout: u.Timeout}
+	conn, err := dialer.DialContext(ctx, u.Family.Network("udp"), net.JoinHostPort(addr.String(), strconv.Itoa(port)))
+	if err != nil {
+		return classifyUDPError(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	payload := udpPayload(port)
+	buf := make([]byte, 1500)
+
+	for attempt := 0; attempt <= u.Retries; attempt++ {
+		if limiter.Wait(ctx) != nil {
+			return PortOpenFiltered
+		}
+
+		if _, err := conn.Write(payload); err != nil {
+			return classifyUDPError(err)
+		}
+
+		_ = conn.SetReadDeadline(time.Now().Add(u.Timeout))
+		_, err := conn.Read(buf)
+		if err == nil {
+			return PortOpen
+		}
+		if state := classifyUDPError(err); state != PortOpenFiltered {
+			return state
+		}
+	}
+	return PortOpenFiltered
+}
+
+// classifyUDPError maps an error from a connected UDP socket to a PortState.
+// The kernel surfaces ICMP port unreachable as ECONNREFUSED.
+func classifyUDPError(err error) PortState {
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return PortClosed
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return PortUnreachable
+	default:
+		// Read deadline exceeded: nothing came back.
+		return PortOpenFiltered
+	}
+}
+
+// udpPayload returns the datagram sent to port.
+func udpPayload(port int) []byte {
+	switch port {
+	case 53:
+		return dnsQueryPayload()
+	case 123:
+		return ntpPayload()
+	case 161:
+		return snmpPayload()
+	default:
+		return []byte{}
+	}
+}
+
+// dnsQueryPayload is a recursive query for the root NS records.
+func dnsQueryPayload() []byte {
+	msg := make([]byte, 17)
+	binary.BigEndian.PutUint16(msg[0:2], uint16(rand.UintN(1<<16))) // ID
+	binary.BigEndian.PutUint16(msg[2:4], 0x0100)                    // RD
+	binary.BigEndian.PutUint16(msg[4:6], 1)                         // QDCOUNT
+	msg[12] = 0                                                     // root name
+	binary.BigEndian.PutUint16(msg[13:15], 2)                       // QTYPE NS
+	binary.BigEndian.PutUint16(msg[15:17], 1)                       // QCLASS IN
+	return msg
+}
+
+// ntpPayload is an NTPv3 client request.
+func ntpPayload() []byte {
+	msg := make([]byte, 48)
+	msg[0] = 0x1b // LI 0, VN 3, mode 3 (client)
+	return msg
+}
+
+// snmpPayload is an SNMPv1 GetRequest for sysDescr.0 with community
+// "public".
+func snmpPayload() []byte {
+	id := rand.Uint32() & 0x7fffffff
+	return []byte{
+		0x30, 0x29, // SEQUENCE
+		0x02, 0x01, 0x00, // version: 1
+		0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c', // community
+		0xa0, 0x1c, // GetRequest PDU
+		0x02, 0x04, byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id), // request-id
+		0x02, 0x01, 0x00, // error-status
+		0x02, 0x01, 0x00, // error-index
+		0x30, 0x0e, // varbind list
+		0x30, 0x0c, // varbind
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, // 1.3.6.1.2.1.1.1.0
+		0x05, 0x00, // NULL
+	}
+}
@@ -0,0 +1,87 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestUDPPayload(t *testing.T) {
+	if got := udpPayload(123); len(got) != 48 || got[0] != 0x1b {
+		t.Errorf("NTP payload = % x, want 48-byte client request", got)
+	}
+	if got := udpPayload(53); len(got) != 17 {
+		t.Errorf("DNS payload length = %d, want 17", len(got))
+	}
+	if got := udpPayload(161); len(got) != int(got[1])+2 {
+		t.Errorf("SNMP payload length %d does not match its BER length %d", len(got), got[1])
+	}
+	if got := udpPayload(9999); len(got) != 0 {
+		t.Errorf("default payload = % x, want empty", got)
+	}
+}
+
+func TestUDPScannerStates(t *testing.T) {
+	// Echo server: open.
+	echo, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = echo.Close() }()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = echo.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	// Bound but never answers: open|filtered.
+	silent, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = silent.Close() }()
+
+	// Nothing bound: the kernel answers ICMP port unreachable.
+	gone, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := gone.LocalAddr().(*net.UDPAddr).Port
+	_ = gone.Close()
+
+	open := echo.LocalAddr().(*net.UDPAddr).Port
+	quiet := silent.LocalAddr().(*net.UDPAddr).Port
+
+	s := &UDPScanner{
+		Host:        "127.0.0.1",
+		Ports:       NewPortSet(open, quiet, closed),
+		Timeout:     200 * time.Millisecond,
+		Concurrency: 3,
+		Rate:        1000,
+	}
+	res, err := s.Probe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := res.ScanData
+	if d.Protocol != "udp" {
+		t.Errorf("Protocol = %q, want udp", d.Protocol)
+	}
+	if !slices.Equal(d.OpenPorts, []int{open}) {
+		t.Errorf("OpenPorts = %v, want [%d]", d.OpenPorts, open)
+	}
+	if !slices.Equal(d.OpenFilteredPorts, []int{quiet}) {
+		t.Errorf("OpenFilteredPorts = %v, want [%d]", d.OpenFilteredPorts, quiet)
+	}
+	if !slices.Equal(d.ClosedPorts, []int{closed}) {
+		t.Errorf("ClosedPorts = %v, want [%d]", d.ClosedPorts, closed)
+	}
+}
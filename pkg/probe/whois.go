@@ -3,6 +3,7 @@ package probe
 import (
 	"context"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -11,6 +12,8 @@ import (
 
 type WhoisProber struct {
 	Domain string
+	// Family restricts the connection to the WHOIS server to IPv4 or IPv6.
+	Family Family
 }
 
 func (w *WhoisProber) Type() string {
@@ -20,7 +23,15 @@ func (w *WhoisProber) Type() string {
 func (w *WhoisProber) Probe(ctx context.Context) (Result, error) {
 	start := time.Now()
 
-	raw, err := whois.Whois(w.Domain)
+	client := whois.DefaultClient
+	if w.Family != FamilyAny {
+		client = whois.NewClient().SetDialer(&familyDialer{
+			Dialer: net.Dialer{Timeout: 30 * time.Second},
+			family: w.Family,
+		})
+	}
+
+	raw, err := client.Whois(w.Domain)
 	if err != nil {
 		return Result{
 			TimeStamp: time.Now(),
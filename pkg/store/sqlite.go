@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	// Pure-Go SQLite driver, registered as "sqlite".
+	_ "modernc.org/sqlite"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS probes (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    probe_type   TEXT    NOT NULL,
+    target       TEXT    NOT NULL,
+    timestamp    INTEGER NOT NULL, -- Unix nanoseconds
+    success      INTEGER NOT NULL,
+    severity     INTEGER NOT NULL,
+    latency_ns   INTEGER NOT NULL,
+    payload_json TEXT    NOT NULL  -- full probe.Result
+);
+
+CREATE INDEX IF NOT EXISTS idx_probes_target_time ON probes(target, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_probes_type_time   ON probes(probe_type, timestamp DESC);
+`
+
+// SQLiteStore is a Store backed by an embedded, pure-Go SQLite database.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// Open opens (or creates) the database at path and applies the schema.
+func Open(path string) (*SQLiteStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	// WAL plus a busy timeout lets a running monitor and one-shot commands
+	// write to the same file without "database is locked" errors.
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to apply schema: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) SaveResult(ctx context.Context, r probe.Result) error {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO probes (probe_type, target, timestamp, success, severity, latency_ns, payload_json)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		r.ProbeType,
+		r.Target,
+		r.TimeStamp.UnixNano(),
+		r.Success,
+		int(r.Severity),
+		int64(r.Latency),
+		string(payload),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save result: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Query(ctx context.Context, q Query) ([]probe.Result, error) {
+	var (
+		where []string
+		args  []any
+	)
+
+	if q.Target != "" {
+		where = append(where, "target = ?")
+		args = append(args, q.Target)
+	}
+	if q.ProbeType != "" {
+		where = append(where, "probe_type = ?")
+		args = append(args, q.ProbeType)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "timestamp >= ?")
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "timestamp < ?")
+		args = append(args, q.Until.UnixNano())
+	}
+
+	stmt := "SELECT payload_json FROM probes"
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	stmt += " ORDER BY timestamp DESC, id DESC"
+	if q.Limit > 0 {
+		stmt += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query results: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []probe.Result
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("failed to read result: %w", err)
+		}
+
+		var r probe.Result
+		if err := json.Unmarshal([]byte(payload), &r); err != nil {
+			return nil, fmt.Errorf("failed to decode result: %w", err)
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query results: %w", err)
+	}
+
+	slices.Reverse(results)
+	return results, nil
+}
+
+func (s *SQLiteStore) Compact(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-retention).UnixNano()
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM probes WHERE timestamp < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old results: %w", err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if deleted > 0 {
+		if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+			return deleted, fmt.Errorf("failed to vacuum database: %w", err)
+		}
+	}
+
+	return deleted, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
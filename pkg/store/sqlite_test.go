@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+func openTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	st, err := Open(filepath.Join(t.TempDir(), "netdiag.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	return st
+}
+
+func TestSaveAndQuery(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	seed := []probe.Result{
+		{TimeStamp: base, ProbeType: "ping", Target: "10.0.0.1", Success: true, Latency: 2 * time.Millisecond,
+			PingData: &probe.PingData{AvgRTT: 2 * time.Millisecond}},
+		{TimeStamp: base.Add(time.Minute), ProbeType: "ping", Target: "10.0.0.1", Success: false, Severity: probe.SeverityError},
+		{TimeStamp: base.Add(2 * time.Minute), ProbeType: "http", Target: "10.0.0.1", Success: true},
+		{TimeStamp: base.Add(3 * time.Minute), ProbeType: "ping", Target: "10.0.0.2", Success: true},
+	}
+	for _, r := range seed {
+		if err := st.SaveResult(ctx, r); err != nil {
+			t.Fatalf("SaveResult() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  int
+	}{
+		{"All", Query{}, 4},
+		{"By target", Query{Target: "10.0.0.1"}, 3},
+		{"By target and type", Query{Target: "10.0.0.1", ProbeType: "ping"}, 2},
+		{"Since", Query{Since: base.Add(90 * time.Second)}, 2},
+		{"Until", Query{Until: base.Add(90 * time.Second)}, 2},
+		{"Limit keeps latest", Query{Limit: 1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := st.Query(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("Query() returned %d results, want %d", len(got), tt.want)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i].TimeStamp.Before(got[i-1].TimeStamp) {
+					t.Errorf("results not in chronological order")
+				}
+			}
+		})
+	}
+
+	latest, _ := st.Query(ctx, Query{Limit: 1})
+	if latest[0].Target != "10.0.0.2" {
+		t.Errorf("Limit returned %q, want most recent result", latest[0].Target)
+	}
+
+	pings, _ := st.Query(ctx, Query{Target: "10.0.0.1", ProbeType: "ping"})
+	if pings[0].PingData == nil || pings[0].PingData.AvgRTT != 2*time.Millisecond {
+		t.Errorf("payload not round-tripped: %+v", pings[0])
+	}
+}
+
+func TestCompact(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	for _, ts := range []time.Time{now.Add(-48 * time.Hour), now.Add(-25 * time.Hour), now.Add(-time.Hour)} {
+		if err := st.SaveResult(ctx, probe.Result{TimeStamp: ts, ProbeType: "ping", Target: "x"}); err != nil {
+			t.Fatalf("SaveResult() error = %v", err)
+		}
+	}
+
+	deleted, err := st.Compact(ctx, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Compact() deleted %d, want 2", deleted)
+	}
+
+	left, _ := st.Query(ctx, Query{})
+	if len(left) != 1 {
+		t.Errorf("%d results left after compaction, want 1", len(left))
+	}
+}
@@ -0,0 +1,34 @@
+// Package store persists probe results so history can be queried later.
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+// Query filters stored results. Zero-valued fields match everything.
+type Query struct {
+	Target    string
+	ProbeType string
+	Since     time.Time
+	Until     time.Time
+	// Limit keeps only the most recent N matching results when positive.
+	Limit int
+}
+
+// Store is a persistent history of probe results.
+type Store interface {
+	// SaveResult appends a result to the history.
+	SaveResult(ctx context.Context, r probe.Result) error
+
+	// Query returns matching results in chronological order.
+	Query(ctx context.Context, q Query) ([]probe.Result, error)
+
+	// Compact deletes results older than the retention period and returns
+	// the number of results removed.
+	Compact(ctx context.Context, retention time.Duration) (int64, error)
+
+	Close() error
+}
@@ -0,0 +1,181 @@
+// Package tui implements the full-screen live dashboard.
+package tui
+
+import (
+	"context"
+	"math"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ARCoder181105/netdiag/pkg/analyze"
+	"github.com/ARCoder181105/netdiag/pkg/monitor"
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+const (
+	historySize = 120
+	maxEvents   = 200
+)
+
+// host is the dashboard state for one target.
+type host struct {
+	target    monitor.Target
+	history   *Ring
+	last      *probe.Result
+	inFlight  bool
+	probes    int
+	successes int
+}
+
+// event is one line of the event log.
+type event struct {
+	time     time.Time
+	target   string
+	severity probe.Severity
+	message  string
+}
+
+// resultMsg carries a finished probe back into the update loop.
+type resultMsg struct {
+	index  int
+	result probe.Result
+}
+
+type tickMsg time.Time
+
+// Model is the Bubble Tea model behind `netdiag dashboard`. Probes run as
+// tea.Cmds, so all state is only ever touched from Update.
+type Model struct {
+	ctx      context.Context
+	hosts    []*host
+	interval time.Duration
+
+	selected int
+	paused   bool
+	events   []event
+
+	width  int
+	height int
+}
+
+// New creates a dashboard that probes every target each interval.
+func New(ctx context.Context, targets []monitor.Target, interval time.Duration) Model {
+	m := Model{ctx: ctx, interval: interval}
+	for _, t := range targets {
+		m.hosts = append(m.hosts, &host{target: t, history: NewRing(historySize)})
+	}
+	return m
+}
+
+func (m Model) Init() tea.Cmd {
+	return tea.Batch(m.probeAll(), m.tick())
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c", "esc":
+			return m, tea.Quit
+		case "up", "k":
+			if m.selected > 0 {
+				m.selected--
+			}
+		case "down", "j":
+			if m.selected < len(m.hosts)-1 {
+				m.selected++
+			}
+		case "p", " ":
+			m.paused = !m.paused
+		case "r":
+			// A forced re-probe runs even while paused.
+			return m, m.probe(m.selected)
+		case "R":
+			return m, m.probeAll()
+		}
+		return m, nil
+
+	case tickMsg:
+		if m.paused {
+			return m, m.tick()
+		}
+		return m, tea.Batch(m.probeAll(), m.tick())
+
+	case resultMsg:
+		m.record(msg.index, msg.result)
+		return m, nil
+	}
+
+	return m, nil
+}
+
+// record stores a result and logs an event when the host changes state.
+func (m *Model) record(i int, r probe.Result) {
+	h := m.hosts[i]
+	h.inFlight = false
+	h.probes++
+
+	if r.Success {
+		h.successes++
+		h.history.Push(float64(analyze.Latency(r)) / float64(time.Millisecond))
+	} else {
+		h.history.Push(math.NaN())
+	}
+
+	if h.last == nil || h.last.Severity != r.Severity || h.last.Success != r.Success {
+		m.events = append(m.events, event{
+			time:     r.TimeStamp,
+			target:   h.target.Name,
+			severity: r.Severity,
+			message:  r.Message,
+		})
+		if len(m.events) > maxEvents {
+			m.events = m.events[len(m.events)-maxEvents:]
+		}
+	}
+
+	h.last = &r
+}
+
+func (m Model) tick() tea.Cmd {
+	return tea.Tick(m.interval, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (m Model) probeAll() tea.Cmd {
+	var cmds []tea.Cmd
+	for i := range m.hosts {
+		cmds = append(cmds, m.probe(i))
+	}
+	return tea.Batch(cmds...)
+}
+
+// probe starts a probe for host i unless one is already running.
+func (m Model) probe(i int) tea.Cmd {
+	if i < 0 || i >= len(m.hosts) || m.hosts[i].inFlight {
+		return nil
+	}
+
+	h := m.hosts[i]
+	h.inFlight = true
+	ctx := m.ctx
+
+	return func() tea.Msg {
+		result, err := h.target.Prober.Probe(ctx)
+		if err != nil {
+			result = probe.Result{
+				Target:    h.target.Name,
+				ProbeType: h.target.Prober.Type(),
+				Success:   false,
+				Severity:  probe.SeverityError,
+				Message:   err.Error(),
+				TimeStamp: time.Now(),
+			}
+		}
+		return resultMsg{index: i, result: result}
+	}
+}
@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"math"
+	"strings"
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Ring is a fixed-size buffer that keeps the most recent values.
+type Ring struct {
+	data []float64
+	next int
+	full bool
+}
+
+// NewRing returns a Ring holding up to size values.
+func NewRing(size int) *Ring {
+	return &Ring{data: make([]float64, size)}
+}
+
+// Push appends v, overwriting the oldest value once the ring is full.
+func (r *Ring) Push(v float64) {
+	r.data[r.next] = v
+	r.next = (r.next + 1) % len(r.data)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Values returns the stored values from oldest to newest.
+func (r *Ring) Values() []float64 {
+	if !r.full {
+		return append([]float64(nil), r.data[:r.next]...)
+	}
+	out := make([]float64, 0, len(r.data))
+	out = append(out, r.data[r.next:]...)
+	return append(out, r.data[:r.next]...)
+}
+
+// Sparkline renders the last width values as block characters scaled between
+// the minimum and maximum of those values. NaN values (failed probes) render
+// as a gap. The result is left-padded to width.
+func Sparkline(values []float64, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Repeat(" ", width-len(values)))
+
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+			b.WriteRune(' ')
+		case hi == lo:
+			b.WriteRune(sparkBlocks[len(sparkBlocks)/2])
+		default:
+			idx := int((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+			b.WriteRune(sparkBlocks[idx])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ARCoder181105/netdiag/pkg/monitor"
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+func TestRing(t *testing.T) {
+	r := NewRing(3)
+	if got := r.Values(); len(got) != 0 {
+		t.Errorf("empty ring Values() = %v", got)
+	}
+
+	for i := 1; i <= 5; i++ {
+		r.Push(float64(i))
+	}
+	if got, want := r.Values(), []float64{3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		width  int
+		want   string
+	}{
+		{"Ramp", []float64{0, 7}, 2, "▁█"},
+		{"Flat", []float64{5, 5, 5}, 3, "▅▅▅"},
+		{"Padded", []float64{1, 2}, 4, "  ▁█"},
+		{"Gap", []float64{1, math.NaN(), 2}, 3, "▁ █"},
+		{"Clipped", []float64{9, 9, 1, 2}, 2, "▁█"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sparkline(tt.values, tt.width); got != tt.want {
+				t.Errorf("Sparkline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type stubProber struct{}
+
+func (stubProber) Type() string { return "ping" }
+
+func (stubProber) Probe(_ context.Context) (probe.Result, error) {
+	return probe.Result{Success: true}, nil
+}
+
+func TestModelUpdate(t *testing.T) {
+	m := New(context.Background(), []monitor.Target{
+		{Name: "a", Prober: stubProber{}},
+		{Name: "b", Prober: stubProber{}},
+	}, time.Second)
+
+	update := func(msg tea.Msg) {
+		next, _ := m.Update(msg)
+		m = next.(Model)
+	}
+
+	update(resultMsg{index: 1, result: probe.Result{
+		Success: true, Severity: probe.SeverityOK, Message: "up",
+		PingData: &probe.PingData{AvgRTT: 12 * time.Millisecond},
+	}})
+	update(resultMsg{index: 1, result: probe.Result{Success: true, Severity: probe.SeverityOK}})
+	update(resultMsg{index: 1, result: probe.Result{Success: false, Severity: probe.SeverityError, Message: "down"}})
+
+	if len(m.events) != 2 {
+		t.Errorf("got %d events, want 2 (initial state and one change)", len(m.events))
+	}
+	if got := m.hosts[1].history.Values(); len(got) != 3 || got[0] != 12 || !math.IsNaN(got[2]) {
+		t.Errorf("history = %v, want [12 ... NaN]", got)
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyDown})
+	if m.selected != 1 {
+		t.Errorf("selected = %d after down, want 1", m.selected)
+	}
+	update(tea.KeyMsg{Type: tea.KeyDown})
+	if m.selected != 1 {
+		t.Errorf("selection moved past the last host")
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if !m.paused {
+		t.Error("p should pause")
+	}
+	if _, cmd := m.Update(tickMsg(time.Now())); cmd == nil {
+		t.Error("paused dashboard must keep ticking")
+	}
+
+	view := m.View()
+	for _, want := range []string{"PAUSED", "Details", "Events", "down"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() missing %q", want)
+		}
+	}
+}
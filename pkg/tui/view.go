@@ -0,0 +1,206 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/ARCoder181105/netdiag/pkg/analyze"
+	"github.com/ARCoder181105/netdiag/pkg/probe"
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("14"))
+	helpStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	pausedStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("11"))
+	paneStyle     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+	paneTitle     = lipgloss.NewStyle().Bold(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	okStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	warnStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	errStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	dimStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+const (
+	sparkWidth  = 30
+	detailWidth = 34
+)
+
+// glyph returns the status symbol for a host's last result.
+func glyph(r *probe.Result) string {
+	if r == nil {
+		return dimStyle.Render("○")
+	}
+	switch {
+	case !r.Success || r.Severity == probe.SeverityError:
+		return errStyle.Render("✗")
+	case r.Severity == probe.SeverityWarning || r.Anomaly != nil:
+		return warnStyle.Render("⚠")
+	case r.Severity == probe.SeverityOK:
+		return okStyle.Render("●")
+	default:
+		return dimStyle.Render("?")
+	}
+}
+
+func (m Model) View() string {
+	header := titleStyle.Render("netdiag dashboard") + "  " +
+		helpStyle.Render("[↑↓] select  [p] pause  [r] re-probe  [R] re-probe all  [q] quit")
+	if m.paused {
+		header += "  " + pausedStyle.Render("PAUSED")
+	}
+
+	top := lipgloss.JoinHorizontal(lipgloss.Top, m.hostsView(), m.detailView())
+	events := m.eventsView(lipgloss.Width(top), lipgloss.Height(header)+lipgloss.Height(top))
+	return lipgloss.JoinVertical(lipgloss.Left, header, top, events)
+}
+
+func (m Model) hostsView() string {
+	nameWidth := 12
+	for _, h := range m.hosts {
+		nameWidth = max(nameWidth, min(len(h.target.Name), 32))
+	}
+
+	lines := []string{paneTitle.Render("Hosts")}
+	for i, h := range m.hosts {
+		latency := dimStyle.Render(fmt.Sprintf("%9s", "-"))
+		if h.last != nil {
+			if h.last.Success {
+				latency = fmt.Sprintf("%9s", formatMs(analyze.Latency(*h.last)))
+			} else {
+				latency = errStyle.Render(fmt.Sprintf("%9s", "DOWN"))
+			}
+		}
+
+		name := truncate(h.target.Name, nameWidth)
+		row := fmt.Sprintf("%-*s %-5s", nameWidth, name, h.target.Prober.Type())
+		if i == m.selected {
+			row = selectedStyle.Render(row)
+		}
+
+		lines = append(lines, fmt.Sprintf("%s %s %s  %s",
+			glyph(h.last), row, latency, Sparkline(h.history.Values(), sparkWidth)))
+	}
+
+	return paneStyle.Render(strings.Join(lines, "\n"))
+}
+
+func (m Model) detailView() string {
+	lines := []string{paneTitle.Render("Details")}
+
+	if len(m.hosts) == 0 {
+		return paneStyle.Width(detailWidth).Render(strings.Join(lines, "\n"))
+	}
+
+	h := m.hosts[m.selected]
+	lines = append(lines,
+		truncate(h.target.Name, detailWidth-2),
+		dimStyle.Render(h.target.Prober.Type()),
+		"",
+	)
+
+	if h.last == nil {
+		lines = append(lines, dimStyle.Render("waiting for first result..."))
+		return paneStyle.Width(detailWidth).Render(strings.Join(lines, "\n"))
+	}
+
+	r := h.last
+	uptime := float64(h.successes) / float64(h.probes) * 100
+	lines = append(lines,
+		row("State", r.Severity.String()),
+		row("Checked", r.TimeStamp.Format(time.TimeOnly)),
+		row("Probes", fmt.Sprintf("%d", h.probes)),
+		row("Uptime", fmt.Sprintf("%.1f%%", uptime)),
+	)
+
+	if d := r.PingData; d != nil {
+		lines = append(lines,
+			"",
+			row("IP", d.ResolvedIP),
+			row("Min", formatMs(d.MinRTT)),
+			row("Avg", formatMs(d.AvgRTT)),
+			row("Max", formatMs(d.MaxRTT)),
+			row("StdDev", formatMs(d.StdDevRTT)),
+			row("Loss", fmt.Sprintf("%.1f%%", d.PacketLoss)),
+		)
+	} else if r.Success {
+		lines = append(lines, "", row("Latency", formatMs(r.Latency)))
+	}
+
+	var samples []time.Duration
+	for _, v := range h.history.Values() {
+		if !math.IsNaN(v) {
+			samples = append(samples, time.Duration(v*float64(time.Millisecond)))
+		}
+	}
+	if len(samples) > 0 {
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		lines = append(lines,
+			"",
+			row("P50", formatMs(analyze.Percentile(samples, 50))),
+			row("P95", formatMs(analyze.Percentile(samples, 95))),
+		)
+	}
+
+	lines = append(lines, "", truncate(r.Message, detailWidth-2))
+	return paneStyle.Width(detailWidth).Render(strings.Join(lines, "\n"))
+}
+
+// eventsView renders the newest events that fit below the used lines.
+func (m Model) eventsView(width, used int) string {
+	rows := 8
+	if m.height > 0 {
+		// Leave room for this pane's title and borders.
+		rows = max(3, m.height-used-3)
+	}
+
+	lines := []string{paneTitle.Render("Events")}
+	start := max(0, len(m.events)-rows)
+	for i := len(m.events) - 1; i >= start; i-- {
+		e := m.events[i]
+		var sym string
+		switch e.severity {
+		case probe.SeverityOK:
+			sym = okStyle.Render("✓")
+		case probe.SeverityWarning:
+			sym = warnStyle.Render("⚠")
+		default:
+			sym = errStyle.Render("✗")
+		}
+		line := fmt.Sprintf("%s %s %s — %s", e.time.Format(time.TimeOnly), sym, e.target, e.message)
+		lines = append(lines, lipgloss.NewStyle().MaxWidth(max(20, width-4)).Render(line))
+	}
+	if len(m.events) == 0 {
+		lines = append(lines, dimStyle.Render("no events yet"))
+	}
+
+	style := paneStyle
+	if width > 2 {
+		style = style.Width(width - 2)
+	}
+	return style.Render(strings.Join(lines, "\n"))
+}
+
+func row(label, value string) string {
+	return dimStyle.Render(fmt.Sprintf("%-8s", label)) + " " + value
+}
+
+func formatMs(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d.Microseconds())/1000.0)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 1 {
+		return string(r[:n])
+	}
+	return string(r[:n-1]) + "…"
+}